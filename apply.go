@@ -14,9 +14,11 @@ import (
 // - Keys with nil values: delete the key or zero the field if possible
 // - Nested maps/structs: recursively apply patches
 //
+// Options are passed through to ApplyToStruct and ApplyToMap.
+//
 // Returns an error if the patch cannot be applied due to type incompatibilities
 // or structural constraints.
-func Apply(target any, patch map[string]any) error {
+func Apply(target any, patch map[string]any, opts ...Option) error {
 	if patch == nil {
 		return nil
 	}
@@ -43,7 +45,7 @@ func Apply(target any, patch map[string]any) error {
 	switch elemVal.Kind() {
 	case reflect.Struct:
 		// For structs, use ApplyToStruct
-		return ApplyToStruct(target, patch)
+		return ApplyToStruct(target, patch, opts...)
 
 	case reflect.Map:
 		// For maps, check if it's map[string]any
@@ -58,7 +60,7 @@ func Apply(target any, patch map[string]any) error {
 		}
 
 		// Apply the patch using ApplyToMap
-		resultMap := ApplyToMap(originalMap, patch)
+		resultMap := ApplyToMap(originalMap, patch, opts...)
 
 		// Replace the map contents
 		elemVal.Set(reflect.ValueOf(resultMap))
@@ -9,9 +9,15 @@ import "reflect"
 // - Keys with nil values: delete the key from the result
 // - Nested maps: recursively apply patches to nested maps
 // - Struct values: if original value is a struct and patch is a map, apply patch to struct using ApplyToStruct
+// - Array values: if original value is a fixed-size array and patch is a map, apply it per index (see DiffArraysByIndex)
 //
 // The original map is not modified; a new map is returned.
-func ApplyToMap(original map[string]any, patch map[string]any) map[string]any {
+// Options are passed through when patching struct values with ApplyToStruct.
+func ApplyToMap(original map[string]any, patch map[string]any, opts ...Option) map[string]any {
+	return applyToMap(original, patch, newOptions(opts))
+}
+
+func applyToMap(original map[string]any, patch map[string]any, o *options) map[string]any {
 	if original == nil && patch == nil {
 		return nil
 	}
@@ -37,7 +43,7 @@ func ApplyToMap(original map[string]any, patch map[string]any) map[string]any {
 				// Both are maps - recursively apply patch
 				originalMap := originalValue.(map[string]any)
 				patchMap := patchValue.(map[string]any)
-				result[key] = ApplyToMap(originalMap, patchMap)
+				result[key] = applyToMap(originalMap, patchMap, o)
 			} else if originalValue, exists := result[key]; exists && isStruct(originalValue) {
 				// Original is a struct, patch is a map - apply patch to struct
 				patchMap := patchValue.(map[string]any)
@@ -49,13 +55,23 @@ func ApplyToMap(original map[string]any, patch map[string]any) map[string]any {
 				structPtr := structCopy.Addr().Interface()
 
 				// Apply the patch to the struct copy
-				if err := ApplyToStruct(structPtr, patchMap); err != nil {
+				if err := applyToStruct(structPtr, patchMap, o); err != nil {
 					// If patching fails, replace with the patch map
 					result[key] = copyValue(patchValue)
 				} else {
 					// Use the patched struct
 					result[key] = structCopy.Interface()
 				}
+			} else if originalValue, exists := result[key]; exists && isArray(originalValue) {
+				// Original is an array, patch is a per-index map - apply to a copy
+				arrayValue := reflect.ValueOf(originalValue)
+				arrayCopy := reflect.New(arrayValue.Type()).Elem()
+				arrayCopy.Set(arrayValue)
+				if err := setArrayField(arrayCopy, patchValue, key, o); err != nil {
+					result[key] = copyValue(patchValue)
+				} else {
+					result[key] = arrayCopy.Interface()
+				}
 			} else {
 				// Original doesn't have a map or struct here, or has different type
 				// Replace with the patch map
@@ -1,9 +1,12 @@
 package structdiff
 
 import (
+	"encoding"
+	"encoding/json"
 	"fmt"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -17,9 +20,16 @@ import (
 // - any fields: accept any value type
 // - Numeric conversions: attempted (like JSON deserialization)
 //
+// Options such as IgnoreUnknownFields, NoStringCoercion, NoWeakTyping and
+// CaseInsensitiveFields adjust how strictly the patch is interpreted.
+//
 // Returns an error if the patch cannot be applied due to type incompatibilities
 // or structural constraints.
-func ApplyToStruct(target any, patch map[string]any) error {
+func ApplyToStruct(target any, patch map[string]any, opts ...Option) error {
+	return applyToStruct(target, patch, newOptions(opts))
+}
+
+func applyToStruct(target any, patch map[string]any, o *options) error {
 	if patch == nil {
 		return nil
 	}
@@ -43,7 +53,7 @@ func ApplyToStruct(target any, patch map[string]any) error {
 
 	// Apply each change in the patch
 	for patchKey, patchValue := range patch {
-		if err := applyFieldPatch(structVal, structType, patchKey, patchValue); err != nil {
+		if err := applyFieldPatch(structVal, structType, patchKey, patchValue, o); err != nil {
 			return fmt.Errorf("failed to apply patch for field %q: %w", patchKey, err)
 		}
 	}
@@ -51,10 +61,13 @@ func ApplyToStruct(target any, patch map[string]any) error {
 	return nil
 }
 
-func applyFieldPatch(structVal reflect.Value, structType reflect.Type, fieldName string, patchValue any) error {
+func applyFieldPatch(structVal reflect.Value, structType reflect.Type, fieldName string, patchValue any, o *options) error {
 	// Find the field by JSON name
-	fieldIndex, field, err := findFieldByJSONName(structType, fieldName)
+	fieldIndex, _, err := findFieldByJSONName(structType, fieldName, o)
 	if err != nil {
+		if o.ignoreUnknownFields {
+			return nil
+		}
 		return err
 	}
 
@@ -63,9 +76,24 @@ func applyFieldPatch(structVal reflect.Value, structType reflect.Type, fieldName
 		return fmt.Errorf("field %q is not settable", fieldName)
 	}
 
+	return applyValuePatch(fieldVal, patchValue, fieldName, o)
+}
+
+// applyValuePatch applies a single patch value to a settable value, recursing
+// into nested struct patches. It is shared by struct fields and container
+// elements.
+func applyValuePatch(fieldVal reflect.Value, patchValue any, fieldName string, o *options) error {
 	// Handle nil patch values (deletions/zeroing)
 	if patchValue == nil {
-		return setFieldToNil(fieldVal, field, fieldName)
+		return setFieldToNil(fieldVal, fieldName)
+	}
+
+	if _, isCycleRef := patchValue.(CycleRef); isCycleRef {
+		return fmt.Errorf("cannot apply cycle reference marker to field %q", fieldName)
+	}
+
+	if isRedactedPatchValue(patchValue) {
+		return fmt.Errorf("cannot apply redacted value to field %q", fieldName)
 	}
 
 	// Handle nested map patches for struct fields
@@ -75,19 +103,120 @@ func applyFieldPatch(structVal reflect.Value, structType reflect.Type, fieldName
 			return fmt.Errorf("cannot get address of struct field %q for nested patching", fieldName)
 		}
 		fieldPtr := fieldVal.Addr().Interface()
-		return ApplyToStruct(fieldPtr, patchMap)
+		return applyToStruct(fieldPtr, patchMap, o)
+	}
+
+	// Handle nested map patches for interface fields holding a patchable value
+	if patchMap, isPatchMap := patchValue.(map[string]any); isPatchMap && fieldVal.Kind() == reflect.Interface && !fieldVal.IsNil() {
+		if applied, err := applyInterfacePatch(fieldVal, patchMap, fieldName, o); applied || err != nil {
+			return err
+		}
 	}
 
 	// Handle pointer fields
 	if fieldVal.Kind() == reflect.Pointer {
-		return setPointerField(fieldVal, patchValue, fieldName)
+		return setPointerField(fieldVal, patchValue, fieldName, o)
 	}
 
 	// Convert and set the value
-	return setFieldValue(fieldVal, patchValue, fieldName)
+	return setFieldValue(fieldVal, patchValue, fieldName, o)
 }
 
-func findFieldByJSONName(structType reflect.Type, jsonName string) (int, reflect.StructField, error) {
+// applyInterfacePatch applies a nested patch map onto the dynamic value of a
+// non-nil interface field, keeping its concrete type instead of replacing it
+// with a map[string]any. Since the dynamic value isn't addressable, the patch
+// is applied to a copy which is then stored back; a pointer to a struct is
+// patched through a copy of its pointee.
+//
+// Only structs, maps, arrays and pointers to structs are patched this way;
+// applied is false for other dynamic values so the caller can assign the patch
+// instead. A patch that cannot describe the dynamic value, because it removes
+// a field the struct cannot lose or has keys the map or array cannot hold, was
+// diffed against a value of another type: it replaces the dynamic value with
+// a map of its entries, leaving out the removed ones. Errors applying any
+// other patch, including those of strict options, are returned.
+func applyInterfacePatch(fieldVal reflect.Value, patchMap map[string]any, fieldName string, o *options) (applied bool, err error) {
+	dynVal := fieldVal.Elem()
+	switch dynVal.Kind() {
+	case reflect.Struct, reflect.Map, reflect.Array:
+	case reflect.Pointer:
+		if dynVal.Type().Elem().Kind() != reflect.Struct {
+			return false, nil
+		}
+	default:
+		return false, nil
+	}
+
+	if !patchFitsValue(dynVal, patchMap, o) {
+		fieldVal.Set(reflect.ValueOf(withoutNilEntries(patchMap)))
+		return true, nil
+	}
+
+	valCopy := reflect.New(dynVal.Type()).Elem()
+	valCopy.Set(dynVal)
+	if err := applyValuePatch(valCopy, patchMap, fieldName, o); err != nil {
+		return true, err
+	}
+	fieldVal.Set(valCopy)
+	return true, nil
+}
+
+// patchFitsValue reports whether a patch map can describe changes to v, a
+// struct, map, array or pointer to a struct: it does not set a non-nillable
+// struct field to nil, and its keys are valid keys of the map or indices of
+// the array. Keys naming no struct field are left for applyToStruct to
+// reject or ignore.
+func patchFitsValue(v reflect.Value, patchMap map[string]any, o *options) bool {
+	t := v.Type()
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	for key, value := range patchMap {
+		switch t.Kind() {
+		case reflect.Struct:
+			if value != nil {
+				continue
+			}
+			if _, field, err := findFieldByJSONName(t, key, o); err == nil {
+				switch field.Type.Kind() {
+				case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
+				default:
+					return false
+				}
+			}
+		case reflect.Map:
+			if _, err := convertMapKey(reflect.ValueOf(key), t.Key(), key, o); err != nil {
+				return false
+			}
+		case reflect.Array:
+			if i, err := strconv.Atoi(key); err != nil || i < 0 || i >= t.Len() {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// withoutNilEntries returns a copy of a patch map without its nil entries,
+// recursively, as the value the patch describes when there is nothing to
+// apply it to.
+func withoutNilEntries(m map[string]any) map[string]any {
+	result := make(map[string]any, len(m))
+	for key, value := range m {
+		switch value := value.(type) {
+		case nil:
+		case map[string]any:
+			result[key] = withoutNilEntries(value)
+		default:
+			result[key] = copyValue(value)
+		}
+	}
+	return result
+}
+
+func findFieldByJSONName(structType reflect.Type, jsonName string, o *options) (int, reflect.StructField, error) {
+	foldIndex := -1
 	for i := 0; i < structType.NumField(); i++ {
 		field := structType.Field(i)
 		if !field.IsExported() {
@@ -103,11 +232,18 @@ func findFieldByJSONName(structType reflect.Type, jsonName string) (int, reflect
 		if fieldJSONName == jsonName {
 			return i, field, nil
 		}
+		// Like encoding/json, an exact match wins over a case-insensitive one
+		if o.caseInsensitive && foldIndex < 0 && strings.EqualFold(fieldJSONName, jsonName) {
+			foldIndex = i
+		}
+	}
+	if foldIndex >= 0 {
+		return foldIndex, structType.Field(foldIndex), nil
 	}
 	return -1, reflect.StructField{}, fmt.Errorf("field %q not found", jsonName)
 }
 
-func setFieldToNil(fieldVal reflect.Value, field reflect.StructField, fieldName string) error {
+func setFieldToNil(fieldVal reflect.Value, fieldName string) error {
 	switch fieldVal.Kind() {
 	case reflect.Pointer, reflect.Slice, reflect.Map, reflect.Interface:
 		// These types can be set to nil
@@ -119,20 +255,28 @@ func setFieldToNil(fieldVal reflect.Value, field reflect.StructField, fieldName
 	}
 }
 
-func setPointerField(fieldVal reflect.Value, patchValue any, fieldName string) error {
+// setPointerField sets a pointer field to a new value built from the patch.
+// A nested patch map is applied onto a copy of the current pointee, if any,
+// so that only the patched fields or entries change; the pointee itself,
+// which may be shared, is left untouched.
+func setPointerField(fieldVal reflect.Value, patchValue any, fieldName string, o *options) error {
 	elemType := fieldVal.Type().Elem()
 
 	// Create new instance of the element type
 	newElem := reflect.New(elemType)
+	if _, isPatchMap := patchValue.(map[string]any); isPatchMap && !fieldVal.IsNil() {
+		newElem.Elem().Set(fieldVal.Elem())
+	}
 
 	// Special case: if patch is a map and element type is a struct, apply patch to struct
 	if patchMap, isPatchMap := patchValue.(map[string]any); isPatchMap && elemType.Kind() == reflect.Struct {
-		if err := ApplyToStruct(newElem.Interface(), patchMap); err != nil {
+		if err := applyToStruct(newElem.Interface(), patchMap, o); err != nil {
 			return err
 		}
 	} else {
-		// Set the value to the dereferenced element
-		if err := setFieldValue(newElem.Elem(), patchValue, fieldName); err != nil {
+		// Set the value to the dereferenced element, which may itself be a
+		// pointer
+		if err := applyValuePatch(newElem.Elem(), patchValue, fieldName, o); err != nil {
 			return err
 		}
 	}
@@ -142,7 +286,7 @@ func setPointerField(fieldVal reflect.Value, patchValue any, fieldName string) e
 	return nil
 }
 
-func setFieldValue(fieldVal reflect.Value, patchValue any, fieldName string) error {
+func setFieldValue(fieldVal reflect.Value, patchValue any, fieldName string, o *options) error {
 	patchVal := reflect.ValueOf(patchValue)
 	fieldType := fieldVal.Type()
 	patchType := patchVal.Type()
@@ -155,7 +299,11 @@ func setFieldValue(fieldVal reflect.Value, patchValue any, fieldName string) err
 			originalMap = fieldVal.Interface().(map[string]any)
 		}
 		patchMap := patchValue.(map[string]any)
-		resultMap := ApplyToMap(originalMap, patchMap)
+		if o.building {
+			fieldVal.Set(reflect.ValueOf(copyMap(patchMap)))
+			return nil
+		}
+		resultMap := applyToMap(originalMap, patchMap, o)
 		fieldVal.Set(reflect.ValueOf(resultMap))
 		return nil
 	}
@@ -180,19 +328,21 @@ func setFieldValue(fieldVal reflect.Value, patchValue any, fieldName string) err
 	// Handle type conversions
 	switch fieldType.Kind() {
 	case reflect.String:
-		return setStringField(fieldVal, patchValue, fieldName)
+		return setStringField(fieldVal, patchValue, fieldName, o)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		return setIntField(fieldVal, patchValue, fieldName)
+		return setIntField(fieldVal, patchValue, fieldName, o)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		return setUintField(fieldVal, patchValue, fieldName)
+		return setUintField(fieldVal, patchValue, fieldName, o)
 	case reflect.Float32, reflect.Float64:
-		return setFloatField(fieldVal, patchValue, fieldName)
+		return setFloatField(fieldVal, patchValue, fieldName, o)
 	case reflect.Bool:
-		return setBoolField(fieldVal, patchValue, fieldName)
+		return setBoolField(fieldVal, patchValue, fieldName, o)
 	case reflect.Slice:
-		return setSliceField(fieldVal, patchValue, fieldName)
+		return setSliceField(fieldVal, patchValue, fieldName, o)
+	case reflect.Array:
+		return setArrayField(fieldVal, patchValue, fieldName, o)
 	case reflect.Map:
-		return setMapField(fieldVal, patchValue, fieldName)
+		return setMapField(fieldVal, patchValue, fieldName, o)
 	default:
 		return fmt.Errorf("cannot convert %T to %s for field %q", patchValue, fieldType, fieldName)
 	}
@@ -224,20 +374,23 @@ func setTimeField(fieldVal reflect.Value, patchValue any, fieldName string) erro
 	}
 }
 
-func setStringField(fieldVal reflect.Value, patchValue any, fieldName string) error {
+func setStringField(fieldVal reflect.Value, patchValue any, fieldName string, o *options) error {
 	switch v := patchValue.(type) {
 	case string:
 		fieldVal.SetString(v)
 	case []byte:
 		fieldVal.SetString(string(v))
 	default:
+		if o.noStringCoercion {
+			return fmt.Errorf("cannot convert %T to string for field %q", patchValue, fieldName)
+		}
 		// Convert other types to string
 		fieldVal.SetString(fmt.Sprintf("%v", patchValue))
 	}
 	return nil
 }
 
-func setIntField(fieldVal reflect.Value, patchValue any, fieldName string) error {
+func setIntField(fieldVal reflect.Value, patchValue any, fieldName string, o *options) error {
 	switch v := patchValue.(type) {
 	case int:
 		fieldVal.SetInt(int64(v))
@@ -256,7 +409,19 @@ func setIntField(fieldVal reflect.Value, patchValue any, fieldName string) error
 		fieldVal.SetInt(int64(v))
 	case float64:
 		fieldVal.SetInt(int64(v))
+	case json.Number:
+		i, err := jsonNumberToInt(v)
+		if err != nil {
+			return fmt.Errorf("%w for field %q", err, fieldName)
+		}
+		if fieldVal.OverflowInt(i) {
+			return fmt.Errorf("number %s overflows %s for field %q", v, fieldVal.Type(), fieldName)
+		}
+		fieldVal.SetInt(i)
 	case string:
+		if o.noWeakTyping {
+			return fmt.Errorf("cannot convert string %q to int for field %q", v, fieldName)
+		}
 		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
 			fieldVal.SetInt(i)
 		} else {
@@ -268,7 +433,7 @@ func setIntField(fieldVal reflect.Value, patchValue any, fieldName string) error
 	return nil
 }
 
-func setUintField(fieldVal reflect.Value, patchValue any, fieldName string) error {
+func setUintField(fieldVal reflect.Value, patchValue any, fieldName string, o *options) error {
 	switch v := patchValue.(type) {
 	case uint:
 		fieldVal.SetUint(uint64(v))
@@ -296,7 +461,19 @@ func setUintField(fieldVal reflect.Value, patchValue any, fieldName string) erro
 			return fmt.Errorf("cannot convert negative value %f to uint for field %q", v, fieldName)
 		}
 		fieldVal.SetUint(uint64(v))
+	case json.Number:
+		u, err := jsonNumberToUint(v)
+		if err != nil {
+			return fmt.Errorf("%w for field %q", err, fieldName)
+		}
+		if fieldVal.OverflowUint(u) {
+			return fmt.Errorf("number %s overflows %s for field %q", v, fieldVal.Type(), fieldName)
+		}
+		fieldVal.SetUint(u)
 	case string:
+		if o.noWeakTyping {
+			return fmt.Errorf("cannot convert string %q to uint for field %q", v, fieldName)
+		}
 		if u, err := strconv.ParseUint(v, 10, 64); err == nil {
 			fieldVal.SetUint(u)
 		} else {
@@ -308,7 +485,7 @@ func setUintField(fieldVal reflect.Value, patchValue any, fieldName string) erro
 	return nil
 }
 
-func setFloatField(fieldVal reflect.Value, patchValue any, fieldName string) error {
+func setFloatField(fieldVal reflect.Value, patchValue any, fieldName string, o *options) error {
 	switch v := patchValue.(type) {
 	case float32:
 		fieldVal.SetFloat(float64(v))
@@ -320,7 +497,16 @@ func setFloatField(fieldVal reflect.Value, patchValue any, fieldName string) err
 	case uint, uint8, uint16, uint32, uint64:
 		uintVal := reflect.ValueOf(v).Uint()
 		fieldVal.SetFloat(float64(uintVal))
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil {
+			return fmt.Errorf("cannot convert number %s to float for field %q", v, fieldName)
+		}
+		fieldVal.SetFloat(f)
 	case string:
+		if o.noWeakTyping {
+			return fmt.Errorf("cannot convert string %q to float for field %q", v, fieldName)
+		}
 		if f, err := strconv.ParseFloat(v, 64); err == nil {
 			fieldVal.SetFloat(f)
 		} else {
@@ -332,23 +518,35 @@ func setFloatField(fieldVal reflect.Value, patchValue any, fieldName string) err
 	return nil
 }
 
-func setBoolField(fieldVal reflect.Value, patchValue any, fieldName string) error {
+func setBoolField(fieldVal reflect.Value, patchValue any, fieldName string, o *options) error {
 	switch v := patchValue.(type) {
 	case bool:
 		fieldVal.SetBool(v)
 	case string:
+		if o.noWeakTyping {
+			return fmt.Errorf("cannot convert string %q to bool for field %q", v, fieldName)
+		}
 		if b, err := strconv.ParseBool(v); err == nil {
 			fieldVal.SetBool(b)
 		} else {
 			return fmt.Errorf("cannot convert string %q to bool for field %q", v, fieldName)
 		}
 	case int, int8, int16, int32, int64:
+		if o.noWeakTyping {
+			return fmt.Errorf("cannot convert %T to bool for field %q", patchValue, fieldName)
+		}
 		intVal := reflect.ValueOf(v).Int()
 		fieldVal.SetBool(intVal != 0)
 	case uint, uint8, uint16, uint32, uint64:
+		if o.noWeakTyping {
+			return fmt.Errorf("cannot convert %T to bool for field %q", patchValue, fieldName)
+		}
 		uintVal := reflect.ValueOf(v).Uint()
 		fieldVal.SetBool(uintVal != 0)
 	case float32, float64:
+		if o.noWeakTyping {
+			return fmt.Errorf("cannot convert %T to bool for field %q", patchValue, fieldName)
+		}
 		floatVal := reflect.ValueOf(v).Float()
 		fieldVal.SetBool(floatVal != 0)
 	default:
@@ -357,7 +555,7 @@ func setBoolField(fieldVal reflect.Value, patchValue any, fieldName string) erro
 	return nil
 }
 
-func setSliceField(fieldVal reflect.Value, patchValue any, fieldName string) error {
+func setSliceField(fieldVal reflect.Value, patchValue any, fieldName string, o *options) error {
 	patchVal := reflect.ValueOf(patchValue)
 
 	if patchVal.Kind() != reflect.Slice && patchVal.Kind() != reflect.Array {
@@ -370,7 +568,7 @@ func setSliceField(fieldVal reflect.Value, patchValue any, fieldName string) err
 		elemVal := newSlice.Index(i)
 		patchElem := patchVal.Index(i).Interface()
 
-		if err := setFieldValue(elemVal, patchElem, fmt.Sprintf("%s[%d]", fieldName, i)); err != nil {
+		if err := applyValuePatch(elemVal, patchElem, fmt.Sprintf("%s[%d]", fieldName, i), o); err != nil {
 			return err
 		}
 	}
@@ -379,7 +577,59 @@ func setSliceField(fieldVal reflect.Value, patchValue any, fieldName string) err
 	return nil
 }
 
-func setMapField(fieldVal reflect.Value, patchValue any, fieldName string) error {
+// setArrayField sets a fixed-size array field. The patch may be either a
+// slice or array of exactly the field's length, converted element by element,
+// or a map of decimal indices to element patches as produced by
+// DiffArraysByIndex, applied onto the existing elements.
+func setArrayField(fieldVal reflect.Value, patchValue any, fieldName string, o *options) error {
+	arrayLen := fieldVal.Len()
+
+	if patchMap, ok := patchValue.(map[string]any); ok {
+		// Work on a copy so a failed patch leaves the field untouched
+		newArray := reflect.New(fieldVal.Type()).Elem()
+		newArray.Set(fieldVal)
+		for key, elemPatch := range patchMap {
+			i, err := strconv.Atoi(key)
+			if err != nil || i < 0 || i >= arrayLen {
+				return fmt.Errorf("invalid index %q for array field %q of length %d", key, fieldName, arrayLen)
+			}
+			if err := applyValuePatch(newArray.Index(i), elemPatch, fmt.Sprintf("%s[%d]", fieldName, i), o); err != nil {
+				return err
+			}
+		}
+		fieldVal.Set(newArray)
+		return nil
+	}
+
+	patchVal := reflect.ValueOf(patchValue)
+	if patchVal.Kind() != reflect.Slice && patchVal.Kind() != reflect.Array {
+		return fmt.Errorf("cannot convert %T to array for field %q", patchValue, fieldName)
+	}
+	if patchVal.Len() != arrayLen {
+		return fmt.Errorf("cannot set array field %q of length %d from %d elements", fieldName, arrayLen, patchVal.Len())
+	}
+
+	newArray := reflect.New(fieldVal.Type()).Elem()
+	for i := 0; i < arrayLen; i++ {
+		elemVal := newArray.Index(i)
+		patchElem := patchVal.Index(i).Interface()
+
+		if err := applyValuePatch(elemVal, patchElem, fmt.Sprintf("%s[%d]", fieldName, i), o); err != nil {
+			return err
+		}
+	}
+
+	fieldVal.Set(newArray)
+	return nil
+}
+
+// setMapField merges a patch map into a map field: keys with nil values are
+// deleted, all other keys are set, and keys absent from the patch are kept.
+// Nested patch maps are applied onto the existing entry, so a struct value
+// only has the patched fields changed.
+// String patch keys are parsed back into the field's key type (see
+// convertMapKey).
+func setMapField(fieldVal reflect.Value, patchValue any, fieldName string, o *options) error {
 	patchVal := reflect.ValueOf(patchValue)
 
 	if patchVal.Kind() != reflect.Map {
@@ -388,29 +638,47 @@ func setMapField(fieldVal reflect.Value, patchValue any, fieldName string) error
 
 	mapType := fieldVal.Type()
 
-	// For all map types, use the original behavior (complete replacement)
+	// Build the result in a copy so a failed patch leaves the field untouched
 	// Note: map[string]any is handled in setFieldValue() with ApplyToMap
-	newMap := reflect.MakeMap(mapType)
+	newMap := reflect.MakeMapWithSize(mapType, fieldVal.Len())
+	if !fieldVal.IsNil() {
+		iter := fieldVal.MapRange()
+		for iter.Next() {
+			newMap.SetMapIndex(iter.Key(), iter.Value())
+		}
+	}
 
 	keyType := mapType.Key()
 	valueType := mapType.Elem()
 
 	for _, key := range patchVal.MapKeys() {
-		mapKey := key
-		mapValue := reflect.New(valueType).Elem()
+		mapKey, err := convertMapKey(key, keyType, fieldName, o)
+		if err != nil {
+			return err
+		}
 
-		// Convert key if necessary
-		if !key.Type().AssignableTo(keyType) {
-			convertedKey := reflect.New(keyType).Elem()
-			if err := setFieldValue(convertedKey, key.Interface(), fmt.Sprintf("%s[key]", fieldName)); err != nil {
-				return fmt.Errorf("cannot convert map key: %w", err)
+		// nil means delete the key, or a nil entry when building a value
+		patchMapValue := patchVal.MapIndex(key).Interface()
+		if patchMapValue == nil {
+			if o.building {
+				newMap.SetMapIndex(mapKey, reflect.Zero(valueType))
+			} else {
+				newMap.SetMapIndex(mapKey, reflect.Value{})
+			}
+			continue
+		}
+
+		mapValue := reflect.New(valueType).Elem()
+		if _, isPatchMap := patchMapValue.(map[string]any); isPatchMap {
+			// Map values aren't addressable, so a nested patch is applied to a
+			// copy of the existing entry which is then stored back
+			if existing := newMap.MapIndex(mapKey); existing.IsValid() {
+				mapValue.Set(existing)
 			}
-			mapKey = convertedKey
 		}
 
 		// Convert value
-		patchMapValue := patchVal.MapIndex(key).Interface()
-		if err := setFieldValue(mapValue, patchMapValue, fmt.Sprintf("%s[%v]", fieldName, key.Interface())); err != nil {
+		if err := applyValuePatch(mapValue, patchMapValue, fmt.Sprintf("%s[%v]", fieldName, key.Interface()), o); err != nil {
 			return err
 		}
 
@@ -420,3 +688,64 @@ func setMapField(fieldVal reflect.Value, patchValue any, fieldName string) error
 	fieldVal.Set(newMap)
 	return nil
 }
+
+// convertMapKey converts a patch map key to the key type of a map field.
+// String keys are parsed back from the fmt.Sprint form used by ToMap and the
+// diff functions: via encoding.TextUnmarshaler if the key type implements it,
+// otherwise by string or numeric conversion according to the key's kind.
+func convertMapKey(key reflect.Value, keyType reflect.Type, fieldName string, o *options) (reflect.Value, error) {
+	if key.Type().AssignableTo(keyType) {
+		return key, nil
+	}
+
+	if key.Kind() != reflect.String {
+		// Non-string keys go through the usual value conversions
+		convertedKey := reflect.New(keyType).Elem()
+		if err := setFieldValue(convertedKey, key.Interface(), fmt.Sprintf("%s[key]", fieldName), o); err != nil {
+			return reflect.Value{}, fmt.Errorf("cannot convert map key: %w", err)
+		}
+		return convertedKey, nil
+	}
+
+	s := key.String()
+	newKey := reflect.New(keyType)
+	if unmarshaler, ok := newKey.Interface().(encoding.TextUnmarshaler); ok {
+		if err := unmarshaler.UnmarshalText([]byte(s)); err != nil {
+			return reflect.Value{}, fmt.Errorf("cannot parse map key %q for field %q: %w", s, fieldName, err)
+		}
+		return newKey.Elem(), nil
+	}
+
+	var err error
+	keyVal := newKey.Elem()
+	switch keyType.Kind() {
+	case reflect.String:
+		keyVal.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		var i int64
+		if i, err = strconv.ParseInt(s, 10, keyType.Bits()); err == nil {
+			keyVal.SetInt(i)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		var u uint64
+		if u, err = strconv.ParseUint(s, 10, keyType.Bits()); err == nil {
+			keyVal.SetUint(u)
+		}
+	case reflect.Float32, reflect.Float64:
+		var f float64
+		if f, err = strconv.ParseFloat(s, keyType.Bits()); err == nil {
+			keyVal.SetFloat(f)
+		}
+	case reflect.Bool:
+		var b bool
+		if b, err = strconv.ParseBool(s); err == nil {
+			keyVal.SetBool(b)
+		}
+	default:
+		return reflect.Value{}, fmt.Errorf("cannot convert map key %q to %s for field %q", s, keyType, fieldName)
+	}
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("cannot convert map key %q to %s for field %q", s, keyType, fieldName)
+	}
+	return keyVal, nil
+}
@@ -1,7 +1,10 @@
 package structdiff
 
 import (
+	"fmt"
 	"reflect"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -542,7 +545,7 @@ func TestApplyToStruct_MapFieldPatching(t *testing.T) {
 		assert.Nil(t, original.Settings)
 	})
 
-	t.Run("non-map[string]any field should use original behavior", func(t *testing.T) {
+	t.Run("typed map fields are merged", func(t *testing.T) {
 		type StructWithStringMap struct {
 			Config map[string]string `json:"config"`
 		}
@@ -564,11 +567,11 @@ func TestApplyToStruct_MapFieldPatching(t *testing.T) {
 		err := ApplyToStruct(original, patch)
 		require.NoError(t, err)
 
-		// Should completely replace, not merge
+		// Should merge like map[string]any fields
 		expected := map[string]string{
 			"key1": "new_value1",
+			"key2": "value2", // untouched
 			"key3": "value3",
-			// key2 should be gone (replaced, not merged)
 		}
 		assert.Equal(t, expected, original.Config)
 	})
@@ -585,3 +588,274 @@ func copyStruct(src any) any {
 	}
 	return src
 }
+
+func TestApplyToStruct_ArrayFields(t *testing.T) {
+	type WithArrays struct {
+		ID     [4]byte    `json:"id"`
+		Coords [3]float64 `json:"coords"`
+		Points [2]struct {
+			X int `json:"x"`
+			Y int `json:"y"`
+		} `json:"points"`
+	}
+
+	t.Run("full replacement from ToMap output", func(t *testing.T) {
+		source := WithArrays{ID: [4]byte{1, 2, 3, 4}, Coords: [3]float64{1.5, 2.5, 3.5}}
+		target := &WithArrays{}
+
+		err := ApplyToStruct(target, ToMap(source))
+		require.NoError(t, err)
+		assert.Equal(t, source, *target)
+	})
+
+	t.Run("element-wise conversion", func(t *testing.T) {
+		target := &WithArrays{}
+		err := ApplyToStruct(target, map[string]any{"coords": []any{1, "2.5", float32(3)}})
+		require.NoError(t, err)
+		assert.Equal(t, [3]float64{1, 2.5, 3}, target.Coords)
+	})
+
+	t.Run("length mismatch", func(t *testing.T) {
+		target := &WithArrays{Coords: [3]float64{1, 2, 3}}
+		err := ApplyToStruct(target, map[string]any{"coords": []any{1.0, 2.0}})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "length 3 from 2 elements")
+		assert.Equal(t, [3]float64{1, 2, 3}, target.Coords)
+	})
+
+	t.Run("per-index patch", func(t *testing.T) {
+		target := &WithArrays{Coords: [3]float64{1, 2, 3}}
+		target.Points[1].X = 10
+		target.Points[1].Y = 20
+
+		err := ApplyToStruct(target, map[string]any{
+			"coords": map[string]any{"1": 5.0},
+			"points": map[string]any{"1": map[string]any{"y": 21}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, [3]float64{1, 5, 3}, target.Coords)
+		assert.Equal(t, 10, target.Points[1].X)
+		assert.Equal(t, 21, target.Points[1].Y)
+	})
+
+	t.Run("per-index patch out of range", func(t *testing.T) {
+		target := &WithArrays{Coords: [3]float64{1, 2, 3}}
+		err := ApplyToStruct(target, map[string]any{"coords": map[string]any{"3": 5.0}})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "invalid index \"3\"")
+		assert.Equal(t, [3]float64{1, 2, 3}, target.Coords)
+	})
+}
+
+type testKey struct {
+	Region string
+	Zone   int
+}
+
+func (k testKey) String() string { return fmt.Sprintf("%s/%d", k.Region, k.Zone) }
+
+func (k *testKey) UnmarshalText(text []byte) error {
+	region, zone, ok := strings.Cut(string(text), "/")
+	if !ok {
+		return fmt.Errorf("invalid key %q", text)
+	}
+	z, err := strconv.Atoi(zone)
+	if err != nil {
+		return err
+	}
+	*k = testKey{Region: region, Zone: z}
+	return nil
+}
+
+func TestApplyToStruct_TypedMapKeys(t *testing.T) {
+	type WithMaps struct {
+		ByID   map[int]string     `json:"by_id"`
+		ByFlag map[bool]float64   `json:"by_flag"`
+		ByKey  map[testKey]string `json:"by_key"`
+	}
+
+	t.Run("numeric and bool keys", func(t *testing.T) {
+		target := &WithMaps{ByID: map[int]string{1: "one", 2: "two"}}
+		err := ApplyToStruct(target, map[string]any{
+			"by_id":   map[string]any{"2": "TWO", "3": "three", "1": nil},
+			"by_flag": map[string]any{"true": 1},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[int]string{2: "TWO", 3: "three"}, target.ByID)
+		assert.Equal(t, map[bool]float64{true: 1}, target.ByFlag)
+	})
+
+	t.Run("TextUnmarshaler keys", func(t *testing.T) {
+		target := &WithMaps{ByKey: map[testKey]string{{"us", 1}: "a"}}
+		err := ApplyToStruct(target, map[string]any{
+			"by_key": map[string]any{"us/1": "b", "eu/2": "c"},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[testKey]string{{"us", 1}: "b", {"eu", 2}: "c"}, target.ByKey)
+	})
+
+	t.Run("invalid key leaves map untouched", func(t *testing.T) {
+		target := &WithMaps{ByID: map[int]string{1: "one"}}
+		err := ApplyToStruct(target, map[string]any{
+			"by_id": map[string]any{"2": "two", "x": "bad"},
+		})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot convert map key \"x\"")
+		assert.Equal(t, map[int]string{1: "one"}, target.ByID)
+	})
+}
+
+func TestApplyToStruct_NestedMapEntries(t *testing.T) {
+	type Server struct {
+		Host string            `json:"host"`
+		Port int               `json:"port"`
+		Tags map[string]string `json:"tags"`
+	}
+	type Config struct {
+		Servers map[string]Server         `json:"servers"`
+		Limits  map[string]map[string]int `json:"limits"`
+	}
+
+	newConfig := func() *Config {
+		return &Config{
+			Servers: map[string]Server{
+				"a": {Host: "a.example.com", Port: 80, Tags: map[string]string{"env": "prod"}},
+				"b": {Host: "b.example.com", Port: 80},
+			},
+			Limits: map[string]map[string]int{"cpu": {"soft": 1, "hard": 2}},
+		}
+	}
+
+	t.Run("patch applied onto existing entry", func(t *testing.T) {
+		target := newConfig()
+		err := ApplyToStruct(target, map[string]any{
+			"servers": map[string]any{"a": map[string]any{"port": 81}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, Server{Host: "a.example.com", Port: 81, Tags: map[string]string{"env": "prod"}}, target.Servers["a"])
+		assert.Equal(t, Server{Host: "b.example.com", Port: 80}, target.Servers["b"])
+	})
+
+	t.Run("new entry built from patch", func(t *testing.T) {
+		target := newConfig()
+		err := ApplyToStruct(target, map[string]any{
+			"servers": map[string]any{"c": map[string]any{"host": "c.example.com", "port": 82}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, Server{Host: "c.example.com", Port: 82}, target.Servers["c"])
+		assert.Len(t, target.Servers, 3)
+	})
+
+	t.Run("nil deletes entry", func(t *testing.T) {
+		target := newConfig()
+		err := ApplyToStruct(target, map[string]any{
+			"servers": map[string]any{"b": nil},
+		})
+		require.NoError(t, err)
+		assert.NotContains(t, target.Servers, "b")
+		assert.Contains(t, target.Servers, "a")
+	})
+
+	t.Run("nested maps merged", func(t *testing.T) {
+		target := newConfig()
+		err := ApplyToStruct(target, map[string]any{
+			"limits":  map[string]any{"cpu": map[string]any{"hard": 4, "soft": nil}},
+			"servers": map[string]any{"a": map[string]any{"tags": map[string]any{"tier": "web"}}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]int{"hard": 4}, target.Limits["cpu"])
+		assert.Equal(t, map[string]string{"env": "prod", "tier": "web"}, target.Servers["a"].Tags)
+	})
+
+	t.Run("original entries not mutated", func(t *testing.T) {
+		original := newConfig()
+		target := &Config{Servers: original.Servers, Limits: original.Limits}
+		err := ApplyToStruct(target, map[string]any{
+			"servers": map[string]any{"a": map[string]any{"tags": map[string]any{"env": "dev"}}},
+			"limits":  map[string]any{"cpu": map[string]any{"hard": 4}},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, "prod", original.Servers["a"].Tags["env"])
+		assert.Equal(t, 2, original.Limits["cpu"]["hard"])
+	})
+
+	t.Run("round-trip with DiffStructs", func(t *testing.T) {
+		old := newConfig()
+		new := newConfig()
+		new.Servers["a"] = Server{Host: "a.example.com", Port: 8080, Tags: map[string]string{"env": "staging"}}
+		delete(new.Servers, "b")
+		new.Limits["mem"] = map[string]int{"hard": 512}
+
+		diff, err := DiffStructs(old, new)
+		require.NoError(t, err)
+
+		require.NoError(t, ApplyToStruct(old, diff))
+		assert.Equal(t, new, old)
+	})
+}
+
+func TestApplyToStruct_PointerMapEntries(t *testing.T) {
+	type Server struct {
+		Host string            `json:"host"`
+		Port int               `json:"port"`
+		Tags map[string]string `json:"tags"`
+	}
+	type Config struct {
+		Servers map[string]*Server `json:"servers"`
+		Primary *Server            `json:"primary"`
+	}
+
+	newConfig := func() *Config {
+		return &Config{
+			Servers: map[string]*Server{
+				"a": {Host: "a.example.com", Port: 80, Tags: map[string]string{"env": "prod"}},
+			},
+			Primary: &Server{Host: "p.example.com", Port: 80},
+		}
+	}
+
+	t.Run("patch applied onto existing pointee", func(t *testing.T) {
+		target := newConfig()
+		err := ApplyToStruct(target, map[string]any{
+			"servers": map[string]any{"a": map[string]any{"port": 81}},
+			"primary": map[string]any{"port": 82},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, &Server{Host: "a.example.com", Port: 81, Tags: map[string]string{"env": "prod"}}, target.Servers["a"])
+		assert.Equal(t, &Server{Host: "p.example.com", Port: 82}, target.Primary)
+	})
+
+	t.Run("original pointee not mutated", func(t *testing.T) {
+		original := newConfig()
+		target := &Config{Servers: map[string]*Server{"a": original.Servers["a"]}, Primary: original.Primary}
+		err := ApplyToStruct(target, map[string]any{
+			"servers": map[string]any{"a": map[string]any{"port": 81}},
+			"primary": map[string]any{"port": 82},
+		})
+		require.NoError(t, err)
+		assert.Equal(t, 80, original.Servers["a"].Port)
+		assert.Equal(t, 80, original.Primary.Port)
+	})
+
+	t.Run("round-trip with DiffStructs", func(t *testing.T) {
+		old := newConfig()
+		new := newConfig()
+		new.Servers["a"].Port = 8080
+		new.Servers["a"].Tags = map[string]string{"tier": "web"}
+		new.Servers["b"] = &Server{Host: "b.example.com"}
+		new.Primary.Host = "q.example.com"
+
+		diff, err := DiffStructs(old, new)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"servers": map[string]any{
+				"a": map[string]any{"port": 8080, "tags": map[string]any{"env": nil, "tier": "web"}},
+				"b": map[string]any{"host": "b.example.com", "port": 0},
+			},
+			"primary": map[string]any{"host": "q.example.com"},
+		}, diff)
+
+		require.NoError(t, ApplyToStruct(old, diff))
+		assert.Equal(t, new, old)
+	})
+}
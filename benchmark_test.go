@@ -145,3 +145,21 @@ func BenchmarkDiff_Simple_Allocs(b *testing.B) {
 		_, _ = DiffStructs(old, new)
 	}
 }
+
+func BenchmarkEqual_Nested_NoChanges(b *testing.B) {
+	testTime := time.Date(2023, 12, 25, 10, 30, 0, 0, time.UTC)
+	old := NestedStruct{
+		User:    SimpleStruct{Name: "John Doe", Age: 30, Email: "john@example.com"},
+		Address: AddressStruct{Street: "123 Main St", City: "NYC", ZipCode: "10001", Country: "USA"},
+		Tags:    []string{"admin", "active"},
+		Meta:    map[string]any{"verified": true, "score": 95.5},
+		Created: testTime,
+	}
+	new := old // Same content
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = Equal(&old, &new)
+	}
+}
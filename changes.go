@@ -0,0 +1,137 @@
+package structdiff
+
+import (
+	"reflect"
+	"sort"
+)
+
+// ChangeKind classifies a Change by whether the value is present in old and
+// new. A map key is present whatever its value, so setting a key to nil is a
+// modification, while nil struct fields are absent, as patches leave them out.
+type ChangeKind int
+
+const (
+	// Modified means the value was present in both old and new and differs.
+	Modified ChangeKind = iota
+
+	// Added means the value is absent in old and present in new.
+	Added
+
+	// Removed means the value is present in old and absent in new.
+	Removed
+)
+
+// String returns "modified", "added" or "removed".
+func (k ChangeKind) String() string {
+	switch k {
+	case Added:
+		return "added"
+	case Removed:
+		return "removed"
+	default:
+		return "modified"
+	}
+}
+
+// Change describes a single difference between two values.
+type Change struct {
+	// Path is the sequence of keys leading to the changed value: JSON field
+	// names for struct fields, stringified keys for maps and decimal indices
+	// for arrays diffed with DiffArraysByIndex. It is empty if the values
+	// compared are themselves a single leaf.
+	Path []string
+
+	Kind ChangeKind
+
+	// Old and New are the values in the same form as they appear in a patch
+	// (see ToMap). Old is nil for additions and New is nil for removals.
+	Old any
+	New any
+
+	// Type is the Go type of the changed value: the declared type for struct
+	// fields and typed map or array elements, otherwise the dynamic type of
+	// New (or of Old for removals). It is nil if unknown.
+	Type reflect.Type
+}
+
+// Changes computes the differences between old and new as a flat list, using
+// the same traversal and rules as Diff: each Change corresponds to one leaf
+// of the patch Diff would return.
+//
+// The list is in a deterministic order: struct fields in declaration order and
+// map keys in sorted order, depth first.
+// Returns (changes, nil) on success, or (nil, error) if an error occurs during diffing.
+func Changes(old, new any, opts ...Option) ([]Change, error) {
+	o := newOptions(opts)
+	o.sortKeys = true
+
+	c := &changeCollector{}
+	if err := newDiffer(o, c, true).diffAny(old, new); err != nil {
+		return nil, err
+	}
+	return c.changes, nil
+}
+
+// ChangesFromPatch converts an existing patch, as produced by Diff, into a flat
+// list of changes, sorted by path.
+//
+// Since a patch only records new values, every Change has a nil Old, nil
+// values are reported as Removed and all others as Modified, and Type is the
+// dynamic type of New. Nested maps are always treated as nested patches, so a
+// map value added whole is reported key by key.
+func ChangesFromPatch(patch map[string]any) []Change {
+	var changes []Change
+	collectPatchChanges(patch, nil, &changes)
+	return changes
+}
+
+func collectPatchChanges(patch map[string]any, path []string, changes *[]Change) {
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		keyPath := append(path[:len(path):len(path)], key)
+		value := patch[key]
+
+		if nested, ok := value.(map[string]any); ok {
+			collectPatchChanges(nested, keyPath, changes)
+			continue
+		}
+
+		kind := Modified
+		if value == nil {
+			kind = Removed
+		}
+		*changes = append(*changes, Change{
+			Path: keyPath,
+			Kind: kind,
+			New:  value,
+			Type: reflect.TypeOf(value),
+		})
+	}
+}
+
+// changeCollector records the changes reported by a differ.
+type changeCollector struct {
+	changes []Change
+}
+
+func (c *changeCollector) enter(path []string) WalkAction {
+	return Continue
+}
+
+func (c *changeCollector) change(path []string, kind ChangeKind, old, new any, typ reflect.Type) WalkAction {
+	c.changes = append(c.changes, Change{
+		Path: append([]string(nil), path...),
+		Kind: kind,
+		Old:  old,
+		New:  new,
+		Type: typ,
+	})
+	return Continue
+}
+
+func (c *changeCollector) leave(path []string) {}
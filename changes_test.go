@@ -0,0 +1,207 @@
+package structdiff
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChanges_Structs(t *testing.T) {
+	type Address struct {
+		Street string `json:"street"`
+		City   string `json:"city"`
+	}
+	type Person struct {
+		Name     string            `json:"name"`
+		Age      int               `json:"age"`
+		Nickname *string           `json:"nickname"`
+		Address  Address           `json:"address"`
+		Tags     []string          `json:"tags"`
+		Labels   map[string]string `json:"labels"`
+	}
+
+	old := Person{
+		Name:     "Alice",
+		Age:      30,
+		Nickname: stringPtr("Al"),
+		Address:  Address{Street: "123 Main St", City: "NYC"},
+		Tags:     []string{"a"},
+		Labels:   map[string]string{"team": "x", "role": "dev", "env": "prod"},
+	}
+	new := Person{
+		Name:    "Alice",
+		Age:     31,
+		Address: Address{Street: "123 Main St", City: "Boston"},
+		Tags:    []string{"a", "b"},
+		Labels:  map[string]string{"team": "y", "env": "prod", "zone": "1"},
+	}
+
+	changes, err := Changes(old, new)
+	require.NoError(t, err)
+
+	expected := []Change{
+		{Path: []string{"age"}, Kind: Modified, Old: 30, New: 31, Type: reflect.TypeOf(0)},
+		{Path: []string{"nickname"}, Kind: Removed, Old: "Al", New: nil, Type: reflect.TypeOf((*string)(nil))},
+		{Path: []string{"address", "city"}, Kind: Modified, Old: "NYC", New: "Boston", Type: reflect.TypeOf("")},
+		{Path: []string{"tags"}, Kind: Modified, Old: []any{"a"}, New: []any{"a", "b"}, Type: reflect.TypeOf([]string{})},
+		{Path: []string{"labels", "role"}, Kind: Removed, Old: "dev", New: nil, Type: reflect.TypeOf("")},
+		{Path: []string{"labels", "team"}, Kind: Modified, Old: "x", New: "y", Type: reflect.TypeOf("")},
+		{Path: []string{"labels", "zone"}, Kind: Added, Old: nil, New: "1", Type: reflect.TypeOf("")},
+	}
+	assert.Equal(t, expected, changes)
+}
+
+func TestChanges_Maps(t *testing.T) {
+	old := map[string]any{
+		"a": 1,
+		"b": map[string]any{"x": 1, "y": 2},
+		"c": "gone",
+	}
+	new := map[string]any{
+		"a": 2,
+		"b": map[string]any{"x": 1, "y": 3},
+		"d": true,
+	}
+
+	changes, err := Changes(old, new)
+	require.NoError(t, err)
+
+	expected := []Change{
+		{Path: []string{"a"}, Kind: Modified, Old: 1, New: 2, Type: reflect.TypeOf(0)},
+		{Path: []string{"b", "y"}, Kind: Modified, Old: 2, New: 3, Type: reflect.TypeOf(0)},
+		{Path: []string{"c"}, Kind: Removed, Old: "gone", New: nil, Type: reflect.TypeOf("")},
+		{Path: []string{"d"}, Kind: Added, Old: nil, New: true, Type: reflect.TypeOf(true)},
+	}
+	assert.Equal(t, expected, changes)
+}
+
+func TestChanges_MatchesDiff(t *testing.T) {
+	old := NestedTestStruct{
+		User: TestStruct{Name: "John", Age: 30, Meta: map[string]any{"k": 1}},
+	}
+	new := NestedTestStruct{
+		User: TestStruct{Name: "Jane", Age: 30, Meta: map[string]any{"k": 2}, Created: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	new.Address.City = "Boston"
+
+	patch, err := Diff(old, new)
+	require.NoError(t, err)
+	changes, err := Changes(old, new)
+	require.NoError(t, err)
+
+	// Every change corresponds to exactly one leaf of the patch
+	fromPatch := ChangesFromPatch(patch.(map[string]any))
+	require.Len(t, changes, len(fromPatch))
+	for _, c := range changes {
+		found := false
+		for _, p := range fromPatch {
+			if reflect.DeepEqual(c.Path, p.Path) {
+				assert.Equal(t, p.New, c.New, "path %v", c.Path)
+				found = true
+			}
+		}
+		assert.True(t, found, "path %v not in patch", c.Path)
+	}
+}
+
+func TestChanges_Deterministic(t *testing.T) {
+	old := map[string]any{}
+	new := map[string]any{}
+	for _, k := range []string{"k", "d", "x", "a", "m", "b", "z", "c"} {
+		new[k] = k
+	}
+
+	first, err := Changes(old, new)
+	require.NoError(t, err)
+	for i := 0; i < 10; i++ {
+		again, err := Changes(old, new)
+		require.NoError(t, err)
+		assert.Equal(t, first, again)
+	}
+	assert.Equal(t, []string{"a"}, first[0].Path)
+	assert.Equal(t, []string{"z"}, first[len(first)-1].Path)
+}
+
+func TestChanges_NilMapValues(t *testing.T) {
+	tests := []struct {
+		name string
+		old  any
+		new  any
+		kind ChangeKind
+	}{
+		{"key added with nil", map[string]any{}, map[string]any{"a": nil}, Added},
+		{"value set to nil", map[string]any{"a": 1}, map[string]any{"a": nil}, Modified},
+		{"nil value set", map[string]any{"a": nil}, map[string]any{"a": 1}, Modified},
+		{"nil value removed", map[string]any{"a": nil}, map[string]any{}, Removed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changes, err := Changes(tt.old, tt.new)
+			require.NoError(t, err)
+			require.Len(t, changes, 1)
+			assert.Equal(t, []string{"a"}, changes[0].Path)
+			assert.Equal(t, tt.kind, changes[0].Kind)
+		})
+	}
+
+	t.Run("typed map value set to nil", func(t *testing.T) {
+		type Doc struct {
+			Counts map[string]*int `json:"counts"`
+		}
+
+		changes, err := Changes(Doc{Counts: map[string]*int{"a": intPtr(1)}}, Doc{Counts: map[string]*int{"a": nil}})
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, []string{"counts", "a"}, changes[0].Path)
+		assert.Equal(t, Modified, changes[0].Kind)
+	})
+}
+
+func TestChanges_EdgeCases(t *testing.T) {
+	t.Run("no changes", func(t *testing.T) {
+		changes, err := Changes(TestStruct{Name: "a"}, TestStruct{Name: "a"})
+		require.NoError(t, err)
+		assert.Empty(t, changes)
+	})
+
+	t.Run("leaf values", func(t *testing.T) {
+		changes, err := Changes(1, 2)
+		require.NoError(t, err)
+		assert.Equal(t, []Change{{Path: nil, Kind: Modified, Old: 1, New: 2, Type: reflect.TypeOf(0)}}, changes)
+	})
+
+	t.Run("cycle error", func(t *testing.T) {
+		_, err := Changes(*newCycleTree("b"), *newCycleTree("c"))
+		assert.ErrorIs(t, err, ErrCycle)
+	})
+}
+
+func TestChangesFromPatch(t *testing.T) {
+	patch := map[string]any{
+		"name": "Jane",
+		"address": map[string]any{
+			"city": "Boston",
+			"zip":  nil,
+		},
+		"age": 31,
+	}
+
+	expected := []Change{
+		{Path: []string{"address", "city"}, Kind: Modified, New: "Boston", Type: reflect.TypeOf("")},
+		{Path: []string{"address", "zip"}, Kind: Removed},
+		{Path: []string{"age"}, Kind: Modified, New: 31, Type: reflect.TypeOf(0)},
+		{Path: []string{"name"}, Kind: Modified, New: "Jane", Type: reflect.TypeOf("")},
+	}
+	assert.Equal(t, expected, ChangesFromPatch(patch))
+	assert.Empty(t, ChangesFromPatch(nil))
+}
+
+func TestChangeKind_String(t *testing.T) {
+	assert.Equal(t, "added", Added.String())
+	assert.Equal(t, "removed", Removed.String())
+	assert.Equal(t, "modified", Modified.String())
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"io"
+
+	"github.com/tsarna/go-structdiff"
+)
+
+// runApply implements "structdiff apply BASE PATCH".
+func runApply(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("apply [flags] BASE PATCH", stderr)
+	input := flags.String("input", "auto", "input format: auto, json or yaml")
+	if !parseArgs(flags, args, 2) {
+		return exitError
+	}
+
+	docs, err := readDocuments(flags, *input, stdin)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	result := structdiff.ApplyToMap(docs[0], docs[1])
+	if err := writeJSON(stdout, result); err != nil {
+		return fail(stderr, err)
+	}
+	return exitSame
+}
+
+// runCompose implements "structdiff compose PATCH1 PATCH2".
+func runCompose(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("compose [flags] PATCH1 PATCH2", stderr)
+	input := flags.String("input", "auto", "input format: auto, json or yaml")
+	if !parseArgs(flags, args, 2) {
+		return exitError
+	}
+
+	docs, err := readDocuments(flags, *input, stdin)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	if err := writeJSON(stdout, composePatches(docs[0], docs[1])); err != nil {
+		return fail(stderr, err)
+	}
+	return exitSame
+}
+
+// composePatches returns a patch with the effect of applying first and then
+// second. Values from second win, except that an object set in both is
+// composed with composeObjects.
+func composePatches(first, second map[string]any) map[string]any {
+	result := make(map[string]any, len(first)+len(second))
+	for key, value := range first {
+		result[key] = value
+	}
+
+	for key, value := range second {
+		nested, isPatch := value.(map[string]any)
+		prev, hadPrev := result[key].(map[string]any)
+		if isPatch && hadPrev {
+			result[key] = composeObjects(prev, nested)
+			continue
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// composeObjects composes the nested patch second onto the object first sets.
+// A patch cannot tell whether first adds that object or patches an existing
+// one, so second is applied to first as to a value, as when it is added:
+// members second removes are dropped from first rather than set to null.
+// Removals of members first does not set are kept, for the object first may
+// patch.
+func composeObjects(first, second map[string]any) map[string]any {
+	result := make(map[string]any, len(first)+len(second))
+	for key, value := range first {
+		result[key] = value
+	}
+
+	for key, value := range second {
+		nested, isPatch := value.(map[string]any)
+		prev, hadPrev := result[key].(map[string]any)
+		switch {
+		case value == nil && first[key] != nil:
+			delete(result, key)
+		case isPatch && hadPrev:
+			result[key] = composeObjects(prev, nested)
+		default:
+			result[key] = value
+		}
+	}
+	return result
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestGolden runs the command for each directory in testdata. A test case
+// directory holds its input files, an "args" file with the command line, in
+// which the names of files in the directory stand for their paths, and the
+// expected output and exit status in "want" and "status".
+func TestGolden(t *testing.T) {
+	dirs, err := filepath.Glob(filepath.Join("testdata", "*"))
+	require.NoError(t, err)
+	require.NotEmpty(t, dirs)
+
+	for _, dir := range dirs {
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			argsData, err := os.ReadFile(filepath.Join(dir, "args"))
+			require.NoError(t, err)
+
+			args := strings.Fields(string(argsData))
+			for i, arg := range args {
+				if _, err := os.Stat(filepath.Join(dir, arg)); err == nil {
+					args[i] = filepath.Join(dir, arg)
+				}
+			}
+
+			var stdout, stderr bytes.Buffer
+			status := run(args, strings.NewReader(""), &stdout, &stderr)
+			require.Empty(t, stderr.String())
+
+			wantFile := filepath.Join(dir, "want")
+			statusFile := filepath.Join(dir, "status")
+			if *update {
+				require.NoError(t, os.WriteFile(wantFile, stdout.Bytes(), 0o644))
+				require.NoError(t, os.WriteFile(statusFile, []byte(strconv.Itoa(status)+"\n"), 0o644))
+				return
+			}
+
+			want, err := os.ReadFile(wantFile)
+			require.NoError(t, err)
+			wantStatus, err := os.ReadFile(statusFile)
+			require.NoError(t, err)
+
+			assert.Equal(t, string(want), stdout.String())
+			assert.Equal(t, strings.TrimSpace(string(wantStatus)), strconv.Itoa(status))
+		})
+	}
+}
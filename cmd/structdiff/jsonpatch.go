@@ -0,0 +1,59 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/tsarna/go-structdiff"
+)
+
+// jsonPatchOp is a single RFC 6902 JSON Patch operation.
+type jsonPatchOp struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	Value any    `json:"value"`
+}
+
+// MarshalJSON leaves out the value of "remove" operations only, so that "add"
+// and "replace" operations setting null keep their value.
+func (op jsonPatchOp) MarshalJSON() ([]byte, error) {
+	if op.Op == "remove" {
+		return json.Marshal(struct {
+			Op   string `json:"op"`
+			Path string `json:"path"`
+		}{op.Op, op.Path})
+	}
+	type plain jsonPatchOp
+	return json.Marshal(plain(op))
+}
+
+// jsonPatch converts changes into JSON Patch operations: additions become
+// "add", removals "remove" and modifications "replace". A value set to null
+// is an addition or modification, not a removal.
+func jsonPatch(changes []structdiff.Change) []jsonPatchOp {
+	ops := make([]jsonPatchOp, 0, len(changes))
+	for _, c := range changes {
+		op := jsonPatchOp{Path: jsonPointer(c.Path), Value: c.New}
+		switch c.Kind {
+		case structdiff.Added:
+			op.Op = "add"
+		case structdiff.Removed:
+			op.Op = "remove"
+		default:
+			op.Op = "replace"
+		}
+		ops = append(ops, op)
+	}
+	return ops
+}
+
+// jsonPointer formats path as an RFC 6901 JSON Pointer.
+func jsonPointer(path []string) string {
+	var sb strings.Builder
+	for _, key := range path {
+		sb.WriteByte('/')
+		key = strings.ReplaceAll(key, "~", "~0")
+		sb.WriteString(strings.ReplaceAll(key, "/", "~1"))
+	}
+	return sb.String()
+}
@@ -0,0 +1,432 @@
+// Command structdiff compares, patches and merges JSON or YAML documents.
+//
+// Usage:
+//
+//	structdiff [diff] [flags] OLD NEW
+//	structdiff apply [flags] BASE PATCH
+//	structdiff merge3 [flags] BASE OURS THEIRS
+//	structdiff compose [flags] PATCH1 PATCH2
+//
+// Arguments are file names, or "-" for standard input. Documents must be
+// JSON or YAML objects; the format is chosen by file extension (.yaml and
+// .yml are YAML) unless --input is given.
+//
+// diff, the default, prints the differences between OLD and NEW, in the
+// format selected with --output:
+//
+//	json         the structdiff patch (the default)
+//	json-patch   an RFC 6902 JSON Patch
+//	merge-patch  an RFC 7386 JSON Merge Patch
+//	report       a human-readable report; see --style
+//
+// As null removes a member in a merge patch, merge-patch fails if NEW sets a
+// value to null; json-patch can express that.
+//
+// Like diff(1), it exits with status 0 if the documents are the same, 1 if
+// they differ and 2 on error.
+//
+// apply prints BASE with PATCH applied, using ApplyToMap.
+//
+// merge3 prints the three-way merge of the changes from BASE to OURS and from
+// BASE to THEIRS. Where both changed the same value differently, the merged
+// document holds a conflict object with the keys "<<<<<<< ours",
+// "||||||| base" and ">>>>>>> theirs", and the exit status is 1.
+//
+// compose prints a single patch with the effect of applying PATCH1 and then
+// PATCH2. Where PATCH2 removes a member of an object PATCH1 sets, the member
+// is dropped from that object, which is right when PATCH1 adds the object but
+// leaves the member alone when it patches an existing one: the patches alone
+// do not tell these apart.
+//
+// Numbers are compared by value, so 1 in a JSON document equals 1 in a YAML
+// one, and JSON numbers keep their precision. Documents and patches are
+// printed as indented JSON.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tsarna/go-structdiff"
+	"gopkg.in/yaml.v3"
+)
+
+// Exit statuses
+const (
+	exitSame  = 0
+	exitDiffs = 1
+	exitError = 2
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// pathList collects the values of a repeatable path flag.
+type pathList []string
+
+func (p *pathList) String() string {
+	return strings.Join(*p, ",")
+}
+
+func (p *pathList) Set(value string) error {
+	*p = append(*p, value)
+	return nil
+}
+
+// run runs the command with the given arguments and returns its exit status.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 0 {
+		switch args[0] {
+		case "diff":
+			return runDiff(args[1:], stdin, stdout, stderr)
+		case "apply":
+			return runApply(args[1:], stdin, stdout, stderr)
+		case "merge3":
+			return runMerge3(args[1:], stdin, stdout, stderr)
+		case "compose":
+			return runCompose(args[1:], stdin, stdout, stderr)
+		}
+	}
+	return runDiff(args, stdin, stdout, stderr)
+}
+
+// newFlagSet returns a flag set for a subcommand, printing errors and usage
+// to stderr.
+func newFlagSet(usage string, stderr io.Writer) *flag.FlagSet {
+	flags := flag.NewFlagSet("structdiff", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprintln(stderr, "usage: structdiff "+usage)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// parseArgs parses args with flags and checks that n arguments remain.
+func parseArgs(flags *flag.FlagSet, args []string, n int) bool {
+	if err := flags.Parse(args); err != nil {
+		return false
+	}
+	if flags.NArg() != n {
+		flags.Usage()
+		return false
+	}
+	return true
+}
+
+// fail reports err and returns the error exit status.
+func fail(stderr io.Writer, err error) int {
+	fmt.Fprintf(stderr, "structdiff: %v\n", err)
+	return exitError
+}
+
+// readDocuments reads the documents named by the remaining arguments of
+// flags.
+func readDocuments(flags *flag.FlagSet, format string, stdin io.Reader) ([]map[string]any, error) {
+	docs := make([]map[string]any, flags.NArg())
+	for i, name := range flags.Args() {
+		doc, err := readDocument(name, format, stdin)
+		if err != nil {
+			return nil, err
+		}
+		docs[i] = doc
+	}
+	return docs, nil
+}
+
+// runDiff implements "structdiff [diff] OLD NEW".
+func runDiff(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("[diff] [flags] OLD NEW", stderr)
+	var ignore, only pathList
+	input := flags.String("input", "auto", "input format: auto, json or yaml")
+	output := flags.String("output", "json", "output format: json, json-patch, merge-patch or report")
+	style := flags.String("style", "unified", "report style: unified, tree, color or markdown")
+	flags.Var(&ignore, "ignore", "dotted `path` to leave out of the comparison (repeatable)")
+	flags.Var(&only, "only", "dotted `path` to restrict the comparison to (repeatable)")
+	quiet := flags.Bool("quiet", false, "print nothing, only set the exit status")
+	if !parseArgs(flags, args, 2) {
+		return exitError
+	}
+
+	docs, err := readDocuments(flags, *input, stdin)
+	if err != nil {
+		return fail(stderr, err)
+	}
+	old, new := docs[0], docs[1]
+
+	if len(only) > 0 {
+		old = selectPaths(old, only)
+		new = selectPaths(new, only)
+	}
+	for _, path := range ignore {
+		deletePath(old, splitPath(path))
+		deletePath(new, splitPath(path))
+	}
+
+	patch, err := structdiff.DiffMaps(old, new, structdiff.NormalizeNumbers())
+	if err != nil {
+		return fail(stderr, err)
+	}
+	status := exitSame
+	if len(patch) > 0 {
+		status = exitDiffs
+	}
+	if *quiet {
+		return status
+	}
+
+	if err := writeOutput(stdout, *output, *style, old, new, patch); err != nil {
+		return fail(stderr, err)
+	}
+	return status
+}
+
+// readDocument reads and decodes the document in the named file, or in stdin
+// if name is "-".
+func readDocument(name, format string, stdin io.Reader) (map[string]any, error) {
+	var data []byte
+	var err error
+	if name == "-" {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(name)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if format == "auto" {
+		format = "json"
+		if ext := strings.ToLower(filepath.Ext(name)); ext == ".yaml" || ext == ".yml" {
+			format = "yaml"
+		}
+	}
+
+	var doc any
+	switch format {
+	case "json":
+		doc, err = decodeJSON(data)
+	case "yaml":
+		err = yaml.Unmarshal(data, &doc)
+		doc = normalizeYAML(doc)
+	default:
+		return nil, fmt.Errorf("unknown input format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+
+	if doc == nil {
+		return map[string]any{}, nil
+	}
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("%s: document is not an object", name)
+	}
+	return m, nil
+}
+
+// decodeJSON decodes a JSON document, with numbers as json.Number so that
+// they keep their precision.
+func decodeJSON(data []byte) (any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return nil, fmt.Errorf("unexpected data after JSON document")
+	}
+	return doc, nil
+}
+
+// normalizeYAML converts the maps with non-string keys that YAML can produce
+// into map[string]any, stringifying the keys.
+func normalizeYAML(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for key, elem := range v {
+			v[key] = normalizeYAML(elem)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, elem := range v {
+			m[fmt.Sprint(key)] = normalizeYAML(elem)
+		}
+		return m
+	case []any:
+		for i, elem := range v {
+			v[i] = normalizeYAML(elem)
+		}
+		return v
+	}
+	return v
+}
+
+// splitPath splits a dotted path into its keys.
+func splitPath(path string) []string {
+	return strings.Split(path, ".")
+}
+
+// deletePath removes the value at path from doc, if present.
+func deletePath(doc map[string]any, path []string) {
+	for _, key := range path[:len(path)-1] {
+		child, ok := doc[key].(map[string]any)
+		if !ok {
+			return
+		}
+		doc = child
+	}
+	delete(doc, path[len(path)-1])
+}
+
+// selectPaths returns a document holding only the values of doc at the
+// given paths.
+func selectPaths(doc map[string]any, paths []string) map[string]any {
+	result := make(map[string]any)
+	for _, p := range paths {
+		path := splitPath(p)
+
+		// Find the value
+		var value any = doc
+		found := true
+		for _, key := range path {
+			m, ok := value.(map[string]any)
+			if !ok {
+				found = false
+				break
+			}
+			if value, ok = m[key]; !ok {
+				found = false
+				break
+			}
+		}
+		if !found {
+			continue
+		}
+
+		// Store it at the same path
+		m := result
+		for _, key := range path[:len(path)-1] {
+			child, ok := m[key].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				m[key] = child
+			}
+			m = child
+		}
+		m[path[len(path)-1]] = value
+	}
+	return result
+}
+
+// writeOutput writes the differences in the selected output format.
+func writeOutput(w io.Writer, output, style string, old, new, patch map[string]any) error {
+	switch output {
+	case "json", "merge-patch":
+		// The patch is a merge patch as long as it sets no value to null,
+		// which a merge patch would take as a removal
+		if output == "merge-patch" {
+			if err := checkMergePatch(old, new); err != nil {
+				return err
+			}
+		}
+		if patch == nil {
+			patch = map[string]any{}
+		}
+		return writeJSON(w, patch)
+	case "json-patch":
+		changes, err := structdiff.Changes(old, new, structdiff.NormalizeNumbers())
+		if err != nil {
+			return err
+		}
+		return writeJSON(w, jsonPatch(changes))
+	case "report":
+		return writeReport(w, style, old, new)
+	}
+	return fmt.Errorf("unknown output format %q", output)
+}
+
+// checkMergePatch returns an error if the changes from old to new set a
+// value to null, or add an object holding null, as RFC 7386 merge patches
+// cannot express that.
+func checkMergePatch(old, new map[string]any) error {
+	changes, err := structdiff.Changes(old, new, structdiff.NormalizeNumbers())
+	if err != nil {
+		return err
+	}
+	for _, c := range changes {
+		if c.Kind != structdiff.Removed && holdsNull(c.New) {
+			return fmt.Errorf("cannot set %q to null in a merge patch; use --output json-patch", jsonPointer(c.Path))
+		}
+	}
+	return nil
+}
+
+// holdsNull reports whether v is null or an object with a null member, at
+// any depth. Nulls within arrays do not count, as arrays are replaced whole.
+func holdsNull(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case map[string]any:
+		for _, elem := range v {
+			if holdsNull(elem) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeReport writes a human-readable report in the given style.
+func writeReport(w io.Writer, style string, old, new map[string]any) error {
+	if style == "markdown" {
+		report, err := structdiff.MarkdownReport(old, new, structdiff.NormalizeNumbers())
+		if err != nil {
+			return err
+		}
+		_, err = io.WriteString(w, report)
+		return err
+	}
+
+	var formatStyle structdiff.FormatStyle
+	switch style {
+	case "unified":
+		formatStyle = structdiff.FormatUnified
+	case "tree":
+		formatStyle = structdiff.FormatTree
+	case "color":
+		formatStyle = structdiff.FormatColor
+	default:
+		return fmt.Errorf("unknown report style %q", style)
+	}
+
+	changes, err := structdiff.Changes(old, new, structdiff.NormalizeNumbers())
+	if err != nil {
+		return err
+	}
+	text, err := structdiff.Format(changes, formatStyle)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, text)
+	return err
+}
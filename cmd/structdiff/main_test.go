@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/tsarna/go-structdiff"
+)
+
+// writeFile writes content to name in a temporary directory and returns its path.
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	return path
+}
+
+// runCommand runs the command and returns its exit status and output.
+func runCommand(args ...string) (int, string, string) {
+	var stdout, stderr bytes.Buffer
+	status := run(args, strings.NewReader(""), &stdout, &stderr)
+	return status, stdout.String(), stderr.String()
+}
+
+const (
+	oldJSON = `{"name": "web", "replicas": 2, "image": {"repo": "nginx", "tag": "1.24"}, "debug": true}`
+	newJSON = `{"name": "web", "replicas": 3, "image": {"repo": "nginx", "tag": "1.25"}, "ports": [80]}`
+)
+
+func TestRun_JSON(t *testing.T) {
+	old := writeFile(t, "old.json", oldJSON)
+	new := writeFile(t, "new.json", newJSON)
+
+	status, out, _ := runCommand(old, new)
+	assert.Equal(t, exitDiffs, status)
+
+	var patch map[string]any
+	require.NoError(t, json.Unmarshal([]byte(out), &patch))
+	assert.Equal(t, map[string]any{
+		"replicas": 3.0,
+		"image":    map[string]any{"tag": "1.25"},
+		"debug":    nil,
+		"ports":    []any{80.0},
+	}, patch)
+}
+
+func TestRun_NoDifferences(t *testing.T) {
+	old := writeFile(t, "old.json", oldJSON)
+
+	status, out, _ := runCommand(old, old)
+	assert.Equal(t, exitSame, status)
+	assert.Equal(t, "{}\n", out)
+
+	status, out, _ = runCommand("--quiet", old, old)
+	assert.Equal(t, exitSame, status)
+	assert.Empty(t, out)
+}
+
+func TestRun_YAML(t *testing.T) {
+	old := writeFile(t, "old.yaml", "name: web\nimage:\n  tag: \"1.24\"\nlimits:\n  1: one\n")
+	new := writeFile(t, "new.json", `{"name": "web", "image": {"tag": "1.25"}, "limits": {"1": "one"}}`)
+
+	status, out, _ := runCommand(old, new)
+	assert.Equal(t, exitDiffs, status)
+	assert.JSONEq(t, `{"image": {"tag": "1.25"}}`, out)
+}
+
+func TestRun_JSONPatch(t *testing.T) {
+	old := writeFile(t, "old.json", oldJSON)
+	new := writeFile(t, "new.json", newJSON)
+
+	status, out, _ := runCommand("--output", "json-patch", old, new)
+	assert.Equal(t, exitDiffs, status)
+	assert.JSONEq(t, `[
+		{"op": "remove", "path": "/debug"},
+		{"op": "replace", "path": "/image/tag", "value": "1.25"},
+		{"op": "add", "path": "/ports", "value": [80]},
+		{"op": "replace", "path": "/replicas", "value": 3}
+	]`, out)
+}
+
+func TestRun_MergePatch(t *testing.T) {
+	old := writeFile(t, "old.json", oldJSON)
+	new := writeFile(t, "new.json", newJSON)
+
+	_, out, _ := runCommand("--output", "merge-patch", old, new)
+	assert.JSONEq(t, `{"replicas": 3, "image": {"tag": "1.25"}, "debug": null, "ports": [80]}`, out)
+}
+
+func TestRun_NullValues(t *testing.T) {
+	old := writeFile(t, "old.json", `{"a": 1, "b": 2}`)
+	new := writeFile(t, "new.json", `{"a": null, "c": null, "d": {"e": null}}`)
+
+	status, out, _ := runCommand("--output", "json-patch", old, new)
+	assert.Equal(t, exitDiffs, status)
+	assert.JSONEq(t, `[
+		{"op": "replace", "path": "/a", "value": null},
+		{"op": "remove", "path": "/b"},
+		{"op": "add", "path": "/c", "value": null},
+		{"op": "add", "path": "/d", "value": {"e": null}}
+	]`, out)
+
+	tests := []struct {
+		name string
+		new  string
+	}{
+		{"set to null", `{"a": null, "b": 2}`},
+		{"added as null", `{"a": 1, "b": 2, "c": null}`},
+		{"added object holding null", `{"a": 1, "b": 2, "d": {"e": null}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, out, stderr := runCommand("--output", "merge-patch", old, writeFile(t, "new.json", tt.new))
+			assert.Equal(t, exitError, status)
+			assert.Empty(t, out)
+			assert.Contains(t, stderr, "merge patch")
+		})
+	}
+
+	// Nulls within arrays are replaced along with them
+	status, out, _ = runCommand("--output", "merge-patch", old, writeFile(t, "list.json", `{"a": [null], "b": 2}`))
+	assert.Equal(t, exitDiffs, status)
+	assert.JSONEq(t, `{"a": [null]}`, out)
+}
+
+func TestRun_Report(t *testing.T) {
+	old := writeFile(t, "old.json", oldJSON)
+	new := writeFile(t, "new.json", newJSON)
+
+	status, out, _ := runCommand("--output", "report", old, new)
+	assert.Equal(t, exitDiffs, status)
+	assert.Equal(t, strings.Join([]string{
+		"- debug: true",
+		`- image.tag: "1.24"`,
+		`+ image.tag: "1.25"`,
+		"+ ports: [80]",
+		"- replicas: 2",
+		"+ replicas: 3",
+	}, "\n")+"\n", out)
+
+	_, out, _ = runCommand("--output", "report", "--style", "markdown", old, new)
+	assert.Contains(t, out, "| replicas | 2 | 3 |")
+}
+
+func TestRun_PathFilters(t *testing.T) {
+	old := writeFile(t, "old.json", oldJSON)
+	new := writeFile(t, "new.json", newJSON)
+
+	t.Run("ignore", func(t *testing.T) {
+		_, out, _ := runCommand("--ignore", "replicas", "--ignore", "image.tag", "--ignore", "debug", old, new)
+		assert.JSONEq(t, `{"ports": [80]}`, out)
+	})
+
+	t.Run("only", func(t *testing.T) {
+		_, out, _ := runCommand("--only", "image.tag", old, new)
+		assert.JSONEq(t, `{"image": {"tag": "1.25"}}`, out)
+	})
+
+	t.Run("nothing left to compare", func(t *testing.T) {
+		status, _, _ := runCommand("--only", "name", old, new)
+		assert.Equal(t, exitSame, status)
+	})
+}
+
+func TestRun_Stdin(t *testing.T) {
+	new := writeFile(t, "new.json", newJSON)
+
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"-", new}, strings.NewReader(newJSON), &stdout, &stderr)
+	assert.Equal(t, exitSame, status)
+}
+
+func TestRun_Errors(t *testing.T) {
+	valid := writeFile(t, "valid.json", oldJSON)
+
+	tests := []struct {
+		name   string
+		args   []string
+		errMsg string
+	}{
+		{"missing arguments", []string{valid}, "usage"},
+		{"missing file", []string{valid, "/nonexistent.json"}, "nonexistent"},
+		{"invalid json", []string{valid, writeFile(t, "bad.json", "{")}, "bad.json"},
+		{"not an object", []string{valid, writeFile(t, "list.json", "[1]")}, "not an object"},
+		{"unknown output", []string{"--output", "xml", valid, valid}, "unknown output format"},
+		{"unknown input", []string{"--input", "toml", valid, valid}, "unknown input format"},
+		{"unknown flag", []string{"--bogus", valid, valid}, "bogus"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, _, stderr := runCommand(tt.args...)
+			assert.Equal(t, exitError, status)
+			assert.Contains(t, stderr, tt.errMsg)
+		})
+	}
+}
+
+func TestJSONPointer(t *testing.T) {
+	assert.Equal(t, "", jsonPointer(nil))
+	assert.Equal(t, "/a/b", jsonPointer([]string{"a", "b"}))
+	assert.Equal(t, "/a~1b/c~0d", jsonPointer([]string{"a/b", "c~d"}))
+}
+
+func TestComposePatches(t *testing.T) {
+	t.Run("patches existing values", func(t *testing.T) {
+		base := map[string]any{"a": 1.0, "b": map[string]any{"x": 1.0, "y": 2.0}, "c": "keep"}
+		p1 := map[string]any{"a": 2.0, "b": map[string]any{"x": nil, "w": 0.0}, "d": true}
+		p2 := map[string]any{"a": nil, "b": map[string]any{"y": nil, "z": 3.0}, "d": false}
+
+		stepwise := structdiff.ApplyToMap(structdiff.ApplyToMap(base, p1), p2)
+		composed := structdiff.ApplyToMap(base, composePatches(p1, p2))
+		assert.Equal(t, stepwise, composed)
+	})
+
+	t.Run("patches added objects", func(t *testing.T) {
+		base := map[string]any{}
+		p1 := map[string]any{"k": map[string]any{"x": 1.0, "y": 2.0, "n": map[string]any{"a": 1.0, "b": 2.0}}}
+		p2 := map[string]any{"k": map[string]any{"y": nil, "n": map[string]any{"b": nil}}}
+
+		stepwise := structdiff.ApplyToMap(structdiff.ApplyToMap(base, p1), p2)
+		composed := structdiff.ApplyToMap(base, composePatches(p1, p2))
+		assert.Equal(t, map[string]any{"k": map[string]any{"x": 1.0, "n": map[string]any{"a": 1.0}}}, stepwise)
+		assert.Equal(t, stepwise, composed)
+	})
+}
+
+func TestMerge3(t *testing.T) {
+	base := map[string]any{"a": 1.0, "list": []any{1.0}}
+	ours := map[string]any{"a": 1.0, "list": []any{1.0, 2.0}, "new": "x"}
+	theirs := map[string]any{"list": []any{1.0, 3.0}, "new": "x"}
+
+	merged, conflicts := merge3(base, ours, theirs)
+	assert.Equal(t, 1, conflicts)
+	assert.Equal(t, map[string]any{
+		"list": conflict{Ours: []any{1.0, 2.0}, Base: []any{1.0}, Theirs: []any{1.0, 3.0}},
+		"new":  "x",
+	}, merged)
+}
+
+func TestRun_ApplyStdin(t *testing.T) {
+	patch := writeFile(t, "patch.json", `{"b": 2}`)
+
+	var stdout, stderr bytes.Buffer
+	status := run([]string{"apply", "-", patch}, strings.NewReader(`{"a": 1}`), &stdout, &stderr)
+	assert.Equal(t, exitSame, status)
+	assert.JSONEq(t, `{"a": 1, "b": 2}`, stdout.String())
+}
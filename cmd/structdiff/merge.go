@@ -0,0 +1,101 @@
+package main
+
+import (
+	"io"
+
+	"github.com/tsarna/go-structdiff"
+)
+
+// conflict is written by merge3 in place of a value changed differently on
+// both sides. Its keys resemble the conflict markers of diff3.
+type conflict struct {
+	Ours   any `json:"<<<<<<< ours"`
+	Base   any `json:"||||||| base"`
+	Theirs any `json:">>>>>>> theirs"`
+}
+
+// runMerge3 implements "structdiff merge3 BASE OURS THEIRS".
+func runMerge3(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := newFlagSet("merge3 [flags] BASE OURS THEIRS", stderr)
+	input := flags.String("input", "auto", "input format: auto, json or yaml")
+	if !parseArgs(flags, args, 3) {
+		return exitError
+	}
+
+	docs, err := readDocuments(flags, *input, stdin)
+	if err != nil {
+		return fail(stderr, err)
+	}
+
+	merged, conflicts := merge3(docs[0], docs[1], docs[2])
+	if err := writeJSON(stdout, merged); err != nil {
+		return fail(stderr, err)
+	}
+	if conflicts > 0 {
+		return exitDiffs
+	}
+	return exitSame
+}
+
+// merge3 merges the changes from base to ours and from base to theirs, and
+// returns the merged document and the number of conflicts. Objects changed
+// on both sides are merged key by key; any other value changed differently on
+// both sides is a conflict, replaced by a conflict object holding the three
+// versions, with null for a missing one.
+func merge3(base, ours, theirs map[string]any) (map[string]any, int) {
+	merged := make(map[string]any)
+	conflicts := 0
+
+	keys := make(map[string]bool)
+	for _, doc := range []map[string]any{base, ours, theirs} {
+		for key := range doc {
+			keys[key] = true
+		}
+	}
+
+	for key := range keys {
+		b, inBase := base[key]
+		o, inOurs := ours[key]
+		t, inTheirs := theirs[key]
+
+		var value any
+		var present bool
+		switch {
+		case sameValue(o, inOurs, t, inTheirs):
+			value, present = o, inOurs
+		case sameValue(o, inOurs, b, inBase):
+			// Only theirs changed
+			value, present = t, inTheirs
+		case sameValue(t, inTheirs, b, inBase):
+			// Only ours changed
+			value, present = o, inOurs
+		default:
+			baseMap, baseIsMap := b.(map[string]any)
+			oursMap, oursIsMap := o.(map[string]any)
+			theirsMap, theirsIsMap := t.(map[string]any)
+			if oursIsMap && theirsIsMap && (baseIsMap || !inBase) {
+				var n int
+				value, n = merge3(baseMap, oursMap, theirsMap)
+				conflicts += n
+			} else {
+				value = conflict{Ours: o, Base: b, Theirs: t}
+				conflicts++
+			}
+			present = true
+		}
+
+		if present {
+			merged[key] = value
+		}
+	}
+	return merged, conflicts
+}
+
+// sameValue reports whether two optional values are both absent, or both
+// present and equal, comparing numbers by value.
+func sameValue(a any, hasA bool, b any, hasB bool) bool {
+	if hasA != hasB {
+		return false
+	}
+	return !hasA || structdiff.Equal(a, b, structdiff.NormalizeNumbers())
+}
@@ -3,6 +3,7 @@ package structdiff
 import (
 	"fmt"
 	"reflect"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -17,36 +18,79 @@ import (
 // - Fields tagged with `json:"-"` are excluded
 // - Nil pointers are omitted
 // - Empty values (0, "", false, []) are included
-func ToMap(v any) map[string]any {
-	result := toMapValue(reflect.ValueOf(v))
-	if result == nil {
+//
+// OmitZero, FlattenKeys, MaxDepth and TimeAsRFC3339 adjust the form of the
+// result. Pointer cycles are handled according to OnCycle. Since ToMap has no
+// error result, in the default CycleError mode it returns nil for a cyclic
+// value.
+func ToMap(v any, opts ...Option) map[string]any {
+	o := newOptions(opts)
+	o.shapeMap = true
+	result, err := toMap(v, o)
+	if err != nil {
 		return nil
 	}
+	if o.flattenKeys && result != nil {
+		flat := make(map[string]any, len(result))
+		flattenInto(flat, "", result)
+		result = flat
+	}
+	return result
+}
+
+func toMap(v any, o *options) (map[string]any, error) {
+	result, err := toMapValue(reflect.ValueOf(v), o)
+	if err != nil {
+		return nil, err
+	}
+	if result == nil {
+		return nil, nil
+	}
 	if mapResult, ok := result.(map[string]any); ok {
-		return mapResult
+		return mapResult, nil
 	}
 	// If result is not a map, return nil (non-struct input)
-	return nil
+	return nil, nil
 }
 
-func toMapValue(v reflect.Value) any {
+func toMapValue(v reflect.Value, o *options) (any, error) {
 	if !v.IsValid() {
-		return nil
+		return nil, nil
+	}
+
+	if len(o.redactPaths) > 0 && !isNilValue(v) && o.redacted(o.path) {
+		return o.redactValue(v)
+	}
+
+	// Below the maximum depth, values are stored as they are, unless they
+	// may hold redacted values
+	if o.shapeMap && o.maxDepth > 0 && o.depth >= o.maxDepth && v.CanInterface() &&
+		!hasRedactedFields(v.Type()) && (len(o.redactPaths) == 0 || !o.redactedBelow(o.path)) {
+		return v.Interface(), nil
 	}
 
 	// Handle pointer: omit if nil, otherwise deref
 	if v.Kind() == reflect.Pointer {
 		if v.IsNil() {
-			return nil
+			return nil, nil
+		}
+		if o.visiting(v) {
+			return o.cycleValue(v)
 		}
-		return toMapValue(v.Elem())
+		o.enterPointer(v)
+		val, err := toMapValue(v.Elem(), o)
+		o.leavePointer(v)
+		return val, err
 	}
 
 	switch v.Kind() {
 	case reflect.Struct:
 		// Special case: time.Time
 		if v.Type() == reflect.TypeOf(time.Time{}) {
-			return v.Interface()
+			if o.shapeMap && o.timeRFC3339 {
+				return v.Interface().(time.Time).Format(time.RFC3339Nano), nil
+			}
+			return v.Interface(), nil
 		}
 
 		m := make(map[string]any)
@@ -66,36 +110,83 @@ func toMapValue(v reflect.Value) any {
 			if fv.Kind() == reflect.Pointer && fv.IsNil() {
 				continue // omit nil pointers
 			}
+			if o.shapeMap && o.omitZero && fv.IsZero() {
+				continue
+			}
 
-			val := toMapValue(fv)
+			if isRedactedField(field) {
+				if !isNilValue(fv) {
+					val, err := o.redactValue(fv)
+					if err != nil {
+						return nil, err
+					}
+					m[name] = val
+				}
+				continue
+			}
+
+			o.pushPath(name)
+			val, err := toMapValue(fv, o)
+			o.popPath()
+			if err != nil {
+				return nil, err
+			}
 			if val != nil {
 				m[name] = val
 			}
 		}
-		return m
+		return m, nil
 
 	case reflect.Slice, reflect.Array:
 		if v.Kind() == reflect.Slice && v.IsNil() {
-			return nil
+			return nil, nil
 		}
 		s := make([]any, v.Len())
 		for i := 0; i < v.Len(); i++ {
-			s[i] = toMapValue(v.Index(i))
+			o.pushPath(strconv.Itoa(i))
+			val, err := toMapValue(v.Index(i), o)
+			o.popPath()
+			if err != nil {
+				return nil, err
+			}
+			s[i] = val
 		}
-		return s
+		return s, nil
 
 	case reflect.Map:
 		if v.IsNil() {
-			return nil
+			return nil, nil
 		}
 		m := make(map[string]any)
 		for _, key := range v.MapKeys() {
-			m[fmt.Sprint(key.Interface())] = toMapValue(v.MapIndex(key))
+			elem := v.MapIndex(key)
+			if o.shapeMap && o.omitZero && elem.IsZero() {
+				continue
+			}
+			name := fmt.Sprint(key.Interface())
+			o.pushPath(name)
+			val, err := toMapValue(elem, o)
+			o.popPath()
+			if err != nil {
+				return nil, err
+			}
+			m[name] = val
+		}
+		return m, nil
+
+	case reflect.Interface:
+		// The dynamic value is converted only when it may hold redacted
+		// values, so that these are replaced
+		if v.IsNil() {
+			return nil, nil
+		}
+		if hasRedactedFields(v.Elem().Type()) || (len(o.redactPaths) > 0 && o.redactedBelow(o.path)) {
+			return toMapValue(v.Elem(), o)
 		}
-		return m
+		return v.Interface(), nil
 
 	default:
-		return v.Interface()
+		return v.Interface(), nil
 	}
 }
 
@@ -103,7 +194,7 @@ func parseName(tag, fallback string) string {
 	if tag == "" {
 		return fallback
 	}
-	name := strings.Split(tag, ",")[0]
+	name, _, _ := strings.Cut(tag, ",")
 	if name == "" {
 		return fallback
 	}
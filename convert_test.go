@@ -426,3 +426,185 @@ func TestToMap_EdgeCases(t *testing.T) {
 		assert.Equal(t, map[string]any{}, result)
 	})
 }
+
+type toMapShapeAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type toMapShapeUser struct {
+	Name    string             `json:"name"`
+	Age     int                `json:"age"`
+	Admin   bool               `json:"admin"`
+	Address toMapShapeAddress  `json:"address"`
+	Work    *toMapShapeAddress `json:"work"`
+	Tags    []string           `json:"tags"`
+	Labels  map[string]string  `json:"labels"`
+	Created time.Time          `json:"created"`
+}
+
+func TestToMap_OmitZero(t *testing.T) {
+	user := toMapShapeUser{
+		Name:    "Ann",
+		Address: toMapShapeAddress{City: "Oslo"},
+		Tags:    []string{},
+		Labels:  map[string]string{"team": "core", "empty": ""},
+	}
+
+	assert.Equal(t, map[string]any{
+		"name":    "Ann",
+		"address": map[string]any{"city": "Oslo"},
+		"tags":    []any{},
+		"labels":  map[string]any{"team": "core"},
+	}, ToMap(user, OmitZero()))
+}
+
+func TestToMap_FlattenKeys(t *testing.T) {
+	user := toMapShapeUser{
+		Name:    "Ann",
+		Address: toMapShapeAddress{Street: "Main", City: "Oslo"},
+		Work:    &toMapShapeAddress{City: "Bergen"},
+		Tags:    []string{"a"},
+		Labels:  map[string]string{"app.kubernetes.io/name": "web", `back\slash`: "x"},
+	}
+
+	assert.Equal(t, map[string]any{
+		"name":                            "Ann",
+		"age":                             0,
+		"admin":                           false,
+		"address.street":                  "Main",
+		"address.city":                    "Oslo",
+		"work.street":                     "",
+		"work.city":                       "Bergen",
+		"tags":                            []any{"a"},
+		`labels.app\.kubernetes\.io/name`: "web",
+		`labels.back\\slash`:              "x",
+		"created":                         time.Time{},
+	}, ToMap(user, FlattenKeys()))
+}
+
+func TestToMap_FlattenKeysKeepsEmptyMaps(t *testing.T) {
+	type Container struct {
+		Labels map[string]string `json:"labels"`
+		Empty  struct{}          `json:"empty"`
+	}
+
+	assert.Equal(t, map[string]any{
+		"labels": map[string]any{},
+		"empty":  map[string]any{},
+	}, ToMap(Container{Labels: map[string]string{}}, FlattenKeys()))
+}
+
+func TestToMap_MaxDepth(t *testing.T) {
+	type Inner struct {
+		Address toMapShapeAddress `json:"address"`
+	}
+	type Outer struct {
+		Name  string         `json:"name"`
+		Inner Inner          `json:"inner"`
+		List  [][]int        `json:"list"`
+		Ptr   *Inner         `json:"ptr"`
+		Map   map[string]any `json:"map"`
+	}
+
+	outer := Outer{
+		Name:  "x",
+		Inner: Inner{Address: toMapShapeAddress{City: "Oslo"}},
+		List:  [][]int{{1, 2}},
+		Ptr:   &Inner{},
+		Map:   map[string]any{"nested": map[string]int{"a": 1}},
+	}
+
+	assert.Equal(t, map[string]any{
+		"name":  "x",
+		"inner": Inner{Address: toMapShapeAddress{City: "Oslo"}},
+		"list":  [][]int{{1, 2}},
+		"ptr":   &Inner{},
+		"map":   map[string]any{"nested": map[string]int{"a": 1}},
+	}, ToMap(outer, MaxDepth(1)))
+
+	assert.Equal(t, map[string]any{
+		"name":  "x",
+		"inner": map[string]any{"address": toMapShapeAddress{City: "Oslo"}},
+		"list":  []any{[]int{1, 2}},
+		"ptr":   map[string]any{"address": toMapShapeAddress{}},
+		"map":   map[string]any{"nested": map[string]int{"a": 1}},
+	}, ToMap(outer, MaxDepth(2)))
+
+	assert.Equal(t, ToMap(outer), ToMap(outer, MaxDepth(0)))
+}
+
+func TestToMap_MaxDepthRedacts(t *testing.T) {
+	type Owner struct {
+		Name     string `json:"name"`
+		Password string `json:"password" diff:"redact"`
+	}
+	type Account struct {
+		Owner   Owner             `json:"owner"`
+		Address toMapShapeAddress `json:"address"`
+		Extra   any               `json:"extra"`
+	}
+
+	account := Account{
+		Owner:   Owner{Name: "alice", Password: "s3cret"},
+		Address: toMapShapeAddress{City: "Oslo"},
+		Extra:   Owner{Name: "bob", Password: "hunter2"},
+	}
+
+	assert.Equal(t, map[string]any{
+		"owner":   map[string]any{"name": "alice", "password": RedactedPlaceholder},
+		"address": toMapShapeAddress{City: "Oslo"},
+		"extra":   map[string]any{"name": "bob", "password": RedactedPlaceholder},
+	}, ToMap(account, MaxDepth(1)))
+
+	account.Extra = toMapShapeAddress{City: "Bergen"}
+	assert.Equal(t, map[string]any{
+		"owner":   map[string]any{"name": RedactedPlaceholder, "password": RedactedPlaceholder},
+		"address": map[string]any{"street": "", "city": RedactedPlaceholder},
+		"extra":   toMapShapeAddress{City: "Bergen"},
+	}, ToMap(account, MaxDepth(1), Redact("owner.name", "address.city")))
+}
+
+func TestToMap_TimeAsRFC3339(t *testing.T) {
+	type Event struct {
+		At    time.Time            `json:"at"`
+		When  *time.Time           `json:"when"`
+		Times map[string]time.Time `json:"times"`
+	}
+
+	at := time.Date(2024, 5, 6, 7, 8, 9, 0, time.UTC)
+	when := time.Date(2024, 5, 6, 7, 8, 9, 500, time.FixedZone("", 3600))
+	event := Event{At: at, When: &when, Times: map[string]time.Time{"x": at}}
+
+	m := ToMap(event, TimeAsRFC3339())
+	assert.Equal(t, map[string]any{
+		"at":    "2024-05-06T07:08:09Z",
+		"when":  "2024-05-06T07:08:09.0000005+01:00",
+		"times": map[string]any{"x": "2024-05-06T07:08:09Z"},
+	}, m)
+
+	restored, err := FromMapOf[Event](m)
+	assert.NoError(t, err)
+	assert.True(t, restored.At.Equal(at))
+	assert.True(t, restored.When.Equal(when))
+}
+
+func TestToMap_ShapeOptionsCombined(t *testing.T) {
+	user := toMapShapeUser{Name: "Ann", Address: toMapShapeAddress{City: "Oslo"}}
+
+	assert.Equal(t, map[string]any{
+		"name":         "Ann",
+		"address.city": "Oslo",
+	}, ToMap(user, OmitZero(), FlattenKeys()))
+}
+
+func TestDiff_IgnoresToMapOptions(t *testing.T) {
+	old := toMapShapeUser{Name: "Ann", Address: toMapShapeAddress{City: "Oslo"}}
+	new := map[string]any{"name": "Ann", "address": map[string]any{"city": "Bergen"}}
+
+	patch, err := Diff(old, new, OmitZero(), FlattenKeys(), MaxDepth(1), TimeAsRFC3339())
+	assert.NoError(t, err)
+	expected, err := Diff(old, new)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, patch)
+}
@@ -0,0 +1,81 @@
+package structdiff
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// ErrCycle is returned (wrapped) when a pointer cycle is found while
+// traversing a value in the CycleError mode.
+var ErrCycle = errors.New("pointer cycle detected")
+
+// CycleMode selects how ToMap and the diff functions handle a pointer that
+// leads back to a value that is already being traversed, such as a child's
+// back-pointer to its parent.
+//
+// Pointers that are merely shared (the same value reachable along two
+// different paths) are not cycles and are traversed each time.
+type CycleMode int
+
+const (
+	// CycleError stops with an error wrapping ErrCycle. This is the default.
+	CycleError CycleMode = iota
+
+	// CycleSkip treats the pointer that closes the cycle as if it were nil.
+	CycleSkip
+
+	// CycleMarker emits a CycleRef in place of the pointer that closes the cycle.
+	CycleMarker
+)
+
+// CycleRef is emitted in place of a pointer that closes a cycle when cycles
+// are handled with CycleMarker. It is a marker only and cannot be applied.
+type CycleRef struct {
+	// Type is the Go type of the pointer, e.g. "*main.Node".
+	Type string `json:"$ref"`
+}
+
+// visitKey identifies a pointer during traversal. The type is part of the key
+// because a pointer to a struct and to its first field share an address.
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// visiting reports whether the pointer v is already being traversed.
+func (o *options) visiting(v reflect.Value) bool {
+	return o.visitStack[visitKey{v.Pointer(), v.Type()}]
+}
+
+// enterPointer marks the pointer v as being traversed.
+func (o *options) enterPointer(v reflect.Value) {
+	if o.visitStack == nil {
+		o.visitStack = make(map[visitKey]bool)
+	}
+	o.visitStack[visitKey{v.Pointer(), v.Type()}] = true
+}
+
+// leavePointer marks the pointer v as no longer being traversed, so that
+// shared references elsewhere in the value are traversed normally.
+func (o *options) leavePointer(v reflect.Value) {
+	delete(o.visitStack, visitKey{v.Pointer(), v.Type()})
+}
+
+// cycleValue returns what to emit for a pointer that closes a cycle.
+func (o *options) cycleValue(v reflect.Value) (any, error) {
+	switch o.cycleMode {
+	case CycleSkip:
+		return nil, nil
+	case CycleMarker:
+		return CycleRef{Type: v.Type().String()}, nil
+	default:
+		return nil, fmt.Errorf("%w through %s", ErrCycle, v.Type())
+	}
+}
+
+// visitPair identifies a pair of pointers compared for equality.
+type visitPair struct {
+	a, b uintptr
+	typ  reflect.Type
+}
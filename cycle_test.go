@@ -0,0 +1,124 @@
+package structdiff
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type cycleNode struct {
+	Name     string       `json:"name"`
+	Parent   *cycleNode   `json:"parent"`
+	Children []*cycleNode `json:"children"`
+}
+
+// newCycleTree builds a parent with two children pointing back at it.
+func newCycleTree(childName string) *cycleNode {
+	parent := &cycleNode{Name: "root"}
+	parent.Children = []*cycleNode{
+		{Name: "a", Parent: parent},
+		{Name: childName, Parent: parent},
+	}
+	return parent
+}
+
+func TestToMap_Cycles(t *testing.T) {
+	t.Run("error mode returns nil", func(t *testing.T) {
+		assert.Nil(t, ToMap(newCycleTree("b")))
+	})
+
+	t.Run("skip mode omits back-pointers", func(t *testing.T) {
+		result := ToMap(newCycleTree("b"), OnCycle(CycleSkip))
+		expected := map[string]any{
+			"name": "root",
+			"children": []any{
+				map[string]any{"name": "a"},
+				map[string]any{"name": "b"},
+			},
+		}
+		assert.Equal(t, expected, result)
+	})
+
+	t.Run("marker mode emits references", func(t *testing.T) {
+		result := ToMap(newCycleTree("b"), OnCycle(CycleMarker))
+		children := result["children"].([]any)
+		assert.Equal(t, CycleRef{Type: "*structdiff.cycleNode"}, children[0].(map[string]any)["parent"])
+	})
+
+	t.Run("self-reference", func(t *testing.T) {
+		node := &cycleNode{Name: "self"}
+		node.Parent = node
+		result := ToMap(node, OnCycle(CycleSkip))
+		assert.Equal(t, map[string]any{"name": "self"}, result)
+	})
+
+	t.Run("shared references are not cycles", func(t *testing.T) {
+		type Pair struct {
+			Left  *cycleNode `json:"left"`
+			Right *cycleNode `json:"right"`
+		}
+		shared := &cycleNode{Name: "shared"}
+		result := ToMap(Pair{Left: shared, Right: shared})
+		expected := map[string]any{
+			"left":  map[string]any{"name": "shared"},
+			"right": map[string]any{"name": "shared"},
+		}
+		assert.Equal(t, expected, result)
+	})
+}
+
+func TestDiffStructs_Cycles(t *testing.T) {
+	t.Run("equal cyclic values", func(t *testing.T) {
+		diff, err := DiffStructs(newCycleTree("b"), newCycleTree("b"))
+		require.NoError(t, err)
+		assert.Empty(t, diff)
+	})
+
+	t.Run("error mode", func(t *testing.T) {
+		_, err := DiffStructs(newCycleTree("b"), newCycleTree("c"))
+		assert.ErrorIs(t, err, ErrCycle)
+		assert.Contains(t, err.Error(), "*structdiff.cycleNode")
+	})
+
+	t.Run("skip mode", func(t *testing.T) {
+		diff, err := DiffStructs(newCycleTree("b"), newCycleTree("c"), OnCycle(CycleSkip))
+		require.NoError(t, err)
+		expected := map[string]any{
+			"children": []any{
+				map[string]any{"name": "a"},
+				map[string]any{"name": "c"},
+			},
+		}
+		assert.Equal(t, expected, diff)
+	})
+
+	t.Run("marker mode", func(t *testing.T) {
+		diff, err := DiffStructs(newCycleTree("b"), newCycleTree("c"), OnCycle(CycleMarker))
+		require.NoError(t, err)
+		children := diff["children"].([]any)
+		assert.Equal(t, CycleRef{Type: "*structdiff.cycleNode"}, children[1].(map[string]any)["parent"])
+
+		err = ApplyToStruct(newCycleTree("b"), diff)
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cycle reference")
+	})
+
+	t.Run("mixed struct and map diff", func(t *testing.T) {
+		_, err := Diff(*newCycleTree("b"), map[string]any{"name": "root"})
+		assert.ErrorIs(t, err, ErrCycle)
+	})
+}
+
+func TestDirectValuesEqual_Cycles(t *testing.T) {
+	a := &cycleNode{Name: "loop"}
+	a.Parent = a
+	b := &cycleNode{Name: "loop"}
+	b.Parent = b
+
+	assert.True(t, directValuesEqual(reflect.ValueOf(a), reflect.ValueOf(b), false))
+
+	b.Name = "other"
+	assert.False(t, directValuesEqual(reflect.ValueOf(a), reflect.ValueOf(b), false))
+}
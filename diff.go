@@ -1,5 +1,7 @@
 package structdiff
 
+import "reflect"
+
 // Diff computes a diff/patch between two values that can be any combination of structs and maps.
 // This is a unified function that automatically handles:
 // - struct vs struct: uses DiffStructs
@@ -16,10 +18,20 @@ package structdiff
 //
 // Returns (nil, nil) if both values are nil or if there are no differences.
 // Returns (result, nil) on success, or (nil, error) if an error occurs during diffing.
-func Diff(old, new any) (any, error) {
+func Diff(old, new any, opts ...Option) (any, error) {
+	b := &patchBuilder{}
+	if err := newDiffer(newOptions(opts), visitorSink{b}, false).diffAny(old, new); err != nil {
+		return nil, err
+	}
+	return b.root, nil
+}
+
+// diffAny reports the differences between two values of any kind, at the
+// current path. It implements Diff.
+func (d *differ) diffAny(old, new any) error {
 	// Handle nil cases
 	if old == nil && new == nil {
-		return nil, nil
+		return nil
 	}
 
 	// Determine the types of old and new values
@@ -30,24 +42,24 @@ func Diff(old, new any) (any, error) {
 
 	// Handle struct-struct case
 	if oldIsStruct && newIsStruct {
-		result, err := DiffStructs(old, new)
-		return result, err
+		return d.diffStructValues(reflect.ValueOf(old), reflect.ValueOf(new))
 	}
 
 	// Handle map-map case
 	if oldIsMap && newIsMap {
-		oldMap := old.(map[string]any)
-		newMap := new.(map[string]any)
-		result, err := DiffMaps(oldMap, newMap)
-		return result, err
+		return d.diffMaps(old.(map[string]any), new.(map[string]any))
 	}
 
 	// Handle mixed cases: convert structs to maps and use DiffMaps
 	var oldMap, newMap map[string]any
 
+	var err error
+	d.syncPath()
 	if oldIsStruct || oldIsMap {
 		if oldIsStruct {
-			oldMap = ToMap(old)
+			if oldMap, err = toMap(old, d.o); err != nil {
+				return err
+			}
 		} else {
 			oldMap = old.(map[string]any)
 		}
@@ -55,7 +67,9 @@ func Diff(old, new any) (any, error) {
 
 	if newIsStruct || newIsMap {
 		if newIsStruct {
-			newMap = ToMap(new)
+			if newMap, err = toMap(new, d.o); err != nil {
+				return err
+			}
 		} else {
 			newMap = new.(map[string]any)
 		}
@@ -63,27 +77,53 @@ func Diff(old, new any) (any, error) {
 
 	// If we have maps to compare, use DiffMaps
 	if oldMap != nil || newMap != nil {
-		result, err := DiffMaps(oldMap, newMap)
-		return result, err
+		return d.diffMaps(oldMap, newMap)
 	}
 
-	// For non-struct, non-map values, do a simple equality check
-	// Use safe comparison to avoid panics
-	equal := safeEqual(old, new)
-	if equal {
-		return nil, nil
+	// For non-struct, non-map values, do a deep equality check
+	if valuesEqual(old, new, d.o.normalizeNumbers) {
+		return nil
 	}
 
-	// Values are different and not structs/maps, return the new value
+	// Values are different and not structs/maps, report the new value
 	// This case handles primitive types, slices, etc.
-	return new, nil
+	d.change(old != nil, new != nil, old, new, typeOf(old, new))
+	return nil
+}
+
+// patchBuilder is the Visitor behind Diff, DiffStructs and DiffMaps. It
+// assembles the patch map from the reported changes; nested maps are created
+// only once a change is found below them, so unchanged nested values are
+// omitted. Old values are not used, and not computed for it.
+type patchBuilder struct {
+	root any
+}
+
+func (b *patchBuilder) Enter(path []string) WalkAction {
+	// The top-level container always yields a map, even if nothing changed
+	if len(path) == 0 && b.root == nil {
+		b.root = make(map[string]any)
+	}
+	return Continue
 }
 
-// safeEqual performs equality comparison without panicking on uncomparable types
-func safeEqual(a, b any) bool {
-	defer func() {
-		// If comparison panics (e.g., comparing slices), we recover and return false
-		recover()
-	}()
-	return a == b
+func (b *patchBuilder) Change(path []string, old, new any) WalkAction {
+	if len(path) == 0 {
+		b.root = new
+		return Continue
+	}
+
+	m := b.root.(map[string]any)
+	for _, key := range path[:len(path)-1] {
+		child, ok := m[key].(map[string]any)
+		if !ok {
+			child = make(map[string]any)
+			m[key] = child
+		}
+		m = child
+	}
+	m[path[len(path)-1]] = new
+	return Continue
 }
+
+func (b *patchBuilder) Leave(path []string) {}
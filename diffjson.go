@@ -0,0 +1,201 @@
+package structdiff
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// jsonNumberType is the reflect.Type of json.Number.
+var jsonNumberType = reflect.TypeOf(json.Number(""))
+
+// DiffJSON computes a diff/patch between two JSON objects, like DiffMaps on
+// their decoded forms. Unlike decoding with json.Unmarshal, numbers are
+// decoded as json.Number, so large integers such as IDs keep their precision,
+// and they are compared by value: 1, 1.0 and 1e0 are equal.
+//
+// Numbers in the patch are json.Number values, which ApplyToStruct stores in
+// integer and floating-point fields without going through float64.
+//
+// A JSON null document is treated as an empty object. Returns an error if
+// either document is not valid JSON or not an object.
+func DiffJSON(old, new []byte, opts ...Option) (map[string]any, error) {
+	oldMap, err := decodeJSONObject(old)
+	if err != nil {
+		return nil, fmt.Errorf("old: %w", err)
+	}
+	newMap, err := decodeJSONObject(new)
+	if err != nil {
+		return nil, fmt.Errorf("new: %w", err)
+	}
+	return DiffMaps(oldMap, newMap, opts...)
+}
+
+// decodeJSONObject decodes a JSON object, with numbers as json.Number.
+func decodeJSONObject(data []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("unexpected data after JSON document")
+	}
+
+	if doc == nil {
+		return nil, nil
+	}
+	m, ok := doc.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("JSON document is a %s, not an object", jsonKind(doc))
+	}
+	return m, nil
+}
+
+// jsonKind names the JSON type of a decoded value.
+func jsonKind(v any) string {
+	switch v.(type) {
+	case []any:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	default:
+		return "number"
+	}
+}
+
+// decimal is a number in normalized scientific form: its value is
+// 0.digits × 10^exp, where digits has no leading or trailing zeros. Zero has
+// no digits and is never negative, so equal numbers have equal decimals.
+type decimal struct {
+	neg    bool
+	digits string
+	exp    int
+}
+
+// parseDecimal parses a number in JSON syntax, without loss of precision.
+func parseDecimal(s string) (decimal, bool) {
+	var d decimal
+	if strings.HasPrefix(s, "-") {
+		d.neg = true
+		s = s[1:]
+	}
+
+	mantissa, exponent := s, ""
+	if i := strings.IndexAny(s, "eE"); i >= 0 {
+		mantissa, exponent = s[:i], s[i+1:]
+		exp, err := strconv.Atoi(exponent)
+		if err != nil {
+			return decimal{}, false
+		}
+		d.exp = exp
+	}
+
+	intPart, fracPart, _ := strings.Cut(mantissa, ".")
+	if intPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return decimal{}, false
+	}
+
+	digits := intPart + fracPart
+	d.exp += len(intPart)
+	for len(digits) > 0 && digits[0] == '0' {
+		digits = digits[1:]
+		d.exp--
+	}
+	digits = strings.TrimRight(digits, "0")
+	if digits == "" {
+		return decimal{}, true
+	}
+	d.digits = digits
+	return d, true
+}
+
+// isDigits reports whether s consists of ASCII digits only.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// integer returns the decimal representation of d without a fraction or
+// exponent, or false if d is not an integer or has more than maxDigits
+// digits.
+func (d decimal) integer(maxDigits int) (string, bool) {
+	if d.digits == "" {
+		return "0", true
+	}
+	if d.exp < len(d.digits) || d.exp > maxDigits {
+		return "", false
+	}
+	s := d.digits + strings.Repeat("0", d.exp-len(d.digits))
+	if d.neg {
+		s = "-" + s
+	}
+	return s, true
+}
+
+// jsonNumbersEqual reports whether two JSON numbers have the same value.
+// Numbers that are not valid JSON are equal only if they are identical.
+func jsonNumbersEqual(a, b json.Number) bool {
+	if a == b {
+		return true
+	}
+	da, okA := parseDecimal(string(a))
+	db, okB := parseDecimal(string(b))
+	return okA && okB && da == db
+}
+
+// jsonNumberToInt converts a JSON number to an int64, failing if it is not an
+// integer or does not fit.
+func jsonNumberToInt(n json.Number) (int64, error) {
+	if i, err := n.Int64(); err == nil {
+		return i, nil
+	}
+	d, ok := parseDecimal(string(n))
+	if !ok {
+		return 0, fmt.Errorf("invalid number %q", string(n))
+	}
+	s, ok := d.integer(19)
+	if !ok {
+		return 0, fmt.Errorf("number %s is not an integer or out of range", n)
+	}
+	i, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("number %s is out of range", n)
+	}
+	return i, nil
+}
+
+// jsonNumberToUint converts a JSON number to a uint64, failing if it is not a
+// non-negative integer or does not fit.
+func jsonNumberToUint(n json.Number) (uint64, error) {
+	if u, err := strconv.ParseUint(string(n), 10, 64); err == nil {
+		return u, nil
+	}
+	d, ok := parseDecimal(string(n))
+	if !ok {
+		return 0, fmt.Errorf("invalid number %q", string(n))
+	}
+	if d.neg {
+		return 0, fmt.Errorf("cannot convert negative value %s to uint", n)
+	}
+	s, ok := d.integer(20)
+	if !ok {
+		return 0, fmt.Errorf("number %s is not an integer or out of range", n)
+	}
+	u, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("number %s is out of range", n)
+	}
+	return u, nil
+}
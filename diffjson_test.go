@@ -0,0 +1,154 @@
+package structdiff
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDiffJSON_PreservesLargeIntegers(t *testing.T) {
+	old := []byte(`{"id": 9007199254740993, "name": "a"}`)
+	new := []byte(`{"id": 9007199254740995, "name": "a"}`)
+
+	patch, err := DiffJSON(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"id": json.Number("9007199254740995")}, patch)
+}
+
+func TestDiffJSON_ComparesNumbersByValue(t *testing.T) {
+	old := []byte(`{"a": 1, "b": 2.50, "c": 100, "d": [0, -0.0], "e": {"x": 1.5}}`)
+	new := []byte(`{"a": 1.0, "b": 2.5, "c": 1e2, "d": [0.0, 0], "e": {"x": 15e-1}}`)
+
+	patch, err := DiffJSON(old, new)
+	require.NoError(t, err)
+	assert.Empty(t, patch)
+}
+
+func TestDiffJSON_Changes(t *testing.T) {
+	old := []byte(`{"a": 1, "b": {"c": "x", "d": true}, "gone": 0}`)
+	new := []byte(`{"a": 1.5, "b": {"c": "y", "d": true}, "added": [1, 2]}`)
+
+	patch, err := DiffJSON(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"a":     json.Number("1.5"),
+		"b":     map[string]any{"c": "y"},
+		"added": []any{json.Number("1"), json.Number("2")},
+		"gone":  nil,
+	}, patch)
+}
+
+func TestDiffJSON_NullDocument(t *testing.T) {
+	patch, err := DiffJSON([]byte(`null`), []byte(`{"a": 1}`))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"a": json.Number("1")}, patch)
+}
+
+func TestDiffJSON_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		old    string
+		new    string
+		errMsg string
+	}{
+		{"invalid old", `{`, `{}`, "old:"},
+		{"invalid new", `{}`, `{"a":}`, "new:"},
+		{"array", `[1]`, `{}`, "array, not an object"},
+		{"trailing data", `{}`, `{} {}`, "unexpected data"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := DiffJSON([]byte(tt.old), []byte(tt.new))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestDiffJSON_ApplyToStruct(t *testing.T) {
+	type Config struct {
+		ID      int64   `json:"id"`
+		Count   uint32  `json:"count"`
+		Ratio   float64 `json:"ratio"`
+		Small   int8    `json:"small"`
+		Version string  `json:"version"`
+	}
+
+	old := []byte(`{"id": 1, "count": 1, "ratio": 0.5, "small": 1, "version": "1"}`)
+	new := []byte(`{"id": 9007199254740993, "count": 4e3, "ratio": 0.25, "small": -5.0, "version": "2"}`)
+
+	patch, err := DiffJSON(old, new)
+	require.NoError(t, err)
+
+	cfg := Config{ID: 1, Count: 1, Ratio: 0.5, Small: 1, Version: "1"}
+	require.NoError(t, ApplyToStruct(&cfg, patch))
+	assert.Equal(t, Config{ID: 9007199254740993, Count: 4000, Ratio: 0.25, Small: -5, Version: "2"}, cfg)
+}
+
+func TestApplyToStruct_JSONNumberErrors(t *testing.T) {
+	type Target struct {
+		Int   int     `json:"int"`
+		Small int8    `json:"small"`
+		Uint  uint    `json:"uint"`
+		Float float64 `json:"float"`
+	}
+
+	tests := []struct {
+		name   string
+		patch  map[string]any
+		errMsg string
+	}{
+		{"fraction", map[string]any{"int": json.Number("1.5")}, "not an integer"},
+		{"overflow int8", map[string]any{"small": json.Number("300")}, "overflows int8"},
+		{"too large", map[string]any{"int": json.Number("1e30")}, "out of range"},
+		{"negative uint", map[string]any{"uint": json.Number("-1")}, "negative"},
+		{"invalid", map[string]any{"int": json.Number("abc")}, "invalid number"},
+		{"float overflow", map[string]any{"float": json.Number("1e400")}, "cannot convert number"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var target Target
+			err := ApplyToStruct(&target, tt.patch)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestDiffStructs_JSONNumberFields(t *testing.T) {
+	type Doc struct {
+		Value json.Number `json:"value"`
+	}
+
+	patch, err := Diff(Doc{Value: "1.0"}, Doc{Value: "1"})
+	require.NoError(t, err)
+	assert.Empty(t, patch)
+	assert.True(t, Equal(Doc{Value: "100"}, Doc{Value: "1e2"}))
+	assert.False(t, Equal(Doc{Value: "100"}, Doc{Value: "101"}))
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		a, b  string
+		equal bool
+	}{
+		{"0", "-0.000", true},
+		{"1", "1.000", true},
+		{"123.45", "12345e-2", true},
+		{"0.001", "1E-3", true},
+		{"1e+2", "100", true},
+		{"-5", "5", false},
+		{"1.0000000000000001", "1", false},
+		{"12345678901234567890", "12345678901234567891", false},
+		{"1e", "1e", true},
+		{"1e", "1", false},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.equal, jsonNumbersEqual(json.Number(tt.a), json.Number(tt.b)), "%s vs %s", tt.a, tt.b)
+	}
+}
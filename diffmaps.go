@@ -1,6 +1,9 @@
 package structdiff
 
-import "reflect"
+import (
+	"encoding/json"
+	"reflect"
+)
 
 // DiffMaps computes a diff/patch from old map to new map.
 // The resulting map contains only the changes needed to transform old into new:
@@ -11,76 +14,146 @@ import "reflect"
 // - Keys only in old: included with nil value (indicates deletion)
 // - Nested maps: recursively diffed using DiffMaps
 // - Struct values: compared using the unified Diff function for any combination of structs and maps
+// - Other values, including typed slices, maps and arrays: compared deeply, as struct fields are
 //
 // Applying all changes in the result to the old map would produce the new map.
 // Returns (result, nil) on success, or (nil, error) if an error occurs during diffing.
-func DiffMaps(old, new map[string]any) (map[string]any, error) {
+func DiffMaps(old, new map[string]any, opts ...Option) (map[string]any, error) {
+	b := &patchBuilder{}
+	if err := newDiffer(newOptions(opts), visitorSink{b}, false).diffMaps(old, new); err != nil {
+		return nil, err
+	}
+	result, _ := b.root.(map[string]any)
+	return result, nil
+}
+
+// diffMaps reports the differences between two maps, at the current path.
+// It implements DiffMaps.
+func (d *differ) diffMaps(old, new map[string]any) error {
 	if old == nil && new == nil {
-		return nil, nil
+		return nil
 	}
-	if old == nil {
-		// Everything in new is an addition
-		result := make(map[string]any)
-		for k, v := range new {
-			result[k] = v
-		}
-		return result, nil
+
+	if !d.enter() {
+		return nil
 	}
-	if new == nil {
-		// Everything in old is a deletion
-		result := make(map[string]any)
-		for k := range old {
-			result[k] = nil
+	defer d.leave()
+
+	if d.o.sortKeys {
+		for _, key := range sortedKeys(old, new) {
+			if d.halted() {
+				return nil
+			}
+			if err := d.diffMapKey(key, old, new); err != nil {
+				return err
+			}
 		}
-		return result, nil
+		return nil
 	}
 
-	result := make(map[string]any)
-
-	// Track which keys we've seen in new
-	seenInNew := make(map[string]bool)
-
 	// Process all keys in new map
-	for key, newVal := range new {
-		seenInNew[key] = true
-		oldVal, existsInOld := old[key]
-
-		if !existsInOld {
-			// Key only exists in new - include it
-			result[key] = newVal
-		} else if !valuesEqual(oldVal, newVal) {
-			// Key exists in both but values differ
-			if (isMap(oldVal) || isStruct(oldVal)) && (isMap(newVal) || isStruct(newVal)) {
-				// Use unified Diff function for any combination of maps and structs
-				diff, err := Diff(oldVal, newVal)
-				if err != nil {
-					return nil, err
-				}
-				if diff != nil {
-					if diffMap, ok := diff.(map[string]any); ok && len(diffMap) > 0 {
-						result[key] = diff
-					}
-				}
-			} else {
-				// Different values (non-map, non-struct) - include new value
-				result[key] = newVal
-			}
+	for key := range new {
+		if d.halted() {
+			return nil
+		}
+		if err := d.diffMapKey(key, old, new); err != nil {
+			return err
 		}
-		// If values are equal, omit from result
 	}
 
 	// Process keys that exist only in old (deletions)
 	for key := range old {
-		if !seenInNew[key] {
-			result[key] = nil
+		if d.halted() {
+			return nil
+		}
+		if _, inNew := new[key]; !inNew {
+			if err := d.diffMapKey(key, old, new); err != nil {
+				return err
+			}
 		}
 	}
 
-	return result, nil
+	return nil
+}
+
+// diffMapKey reports the differences for a single key of two maps, either of
+// which may be nil.
+func (d *differ) diffMapKey(key string, old, new map[string]any) error {
+	oldVal, existsInOld := old[key]
+	newVal, existsInNew := new[key]
+
+	if d.redactedAt(key) {
+		var oldV, newV reflect.Value
+		if existsInOld {
+			oldV = reflect.ValueOf(oldVal)
+		}
+		if existsInNew {
+			newV = reflect.ValueOf(newVal)
+		}
+		equal := existsInOld && existsInNew && valuesEqual(oldVal, newVal, d.o.normalizeNumbers)
+		return d.diffRedacted(key, oldV, newV, equal, typeOf(oldVal, newVal))
+	}
+
+	if !existsInNew {
+		// Key only exists in old - deletion
+		return d.emitRaw(key, true, false, oldVal, nil)
+	}
+
+	if !existsInOld {
+		// Key only exists in new - include it
+		return d.emitRaw(key, false, true, nil, newVal)
+	}
+
+	if valuesEqual(oldVal, newVal, d.o.normalizeNumbers) {
+		// If values are equal, omit from result
+		return nil
+	}
+
+	// Key exists in both but values differ
+	if (isMap(oldVal) || isStruct(oldVal)) && (isMap(newVal) || isStruct(newVal)) {
+		// Use unified Diff function for any combination of maps and structs
+		d.push(key)
+		defer d.pop()
+		return d.diffAny(oldVal, newVal)
+	}
+
+	if d.o.arrayIndexDiff && isArray(oldVal) && reflect.TypeOf(oldVal) == reflect.TypeOf(newVal) {
+		// Same-typed arrays - emit only the changed indices
+		d.push(key)
+		defer d.pop()
+		return d.diffArrayValues(reflect.ValueOf(oldVal), reflect.ValueOf(newVal))
+	}
+
+	// Different values (non-map, non-struct) - include new value
+	return d.emitRaw(key, true, true, oldVal, newVal)
 }
 
-// valuesEqual compares two values for equality safely, handling uncomparable types
-func valuesEqual(a, b any) bool {
+// emitRaw reports a change at the current path extended by key between two
+// values taken as is from maps.
+func (d *differ) emitRaw(key string, hadOld, hasNew bool, oldVal, newVal any) error {
+	typ := typeOf(oldVal, newVal)
+	d.push(key)
+	defer d.pop()
+
+	var old any
+	if d.wantOld {
+		var err error
+		if old, err = d.rawValue(oldVal); err != nil {
+			return err
+		}
+	}
+	new, err := d.rawValue(newVal)
+	if err != nil {
+		return err
+	}
+	d.change(hadOld, hasNew, old, new, typ)
+	return nil
+}
+
+// valuesEqual compares two values for equality safely, handling uncomparable
+// types. With numeric set, values of different types are compared as by
+// NormalizeNumbers.
+func valuesEqual(a, b any, numeric bool) bool {
 	if a == nil && b == nil {
 		return true
 	}
@@ -92,7 +165,7 @@ func valuesEqual(a, b any) bool {
 	if isMap(a) && isMap(b) {
 		mapA := a.(map[string]any)
 		mapB := b.(map[string]any)
-		return mapsEqual(mapA, mapB)
+		return mapsEqual(mapA, mapB, numeric)
 	}
 
 	// For slices, we need deep comparison
@@ -100,33 +173,41 @@ func valuesEqual(a, b any) bool {
 		sliceA, okA := a.([]any)
 		sliceB, okB := b.([]any)
 		if okA && okB {
-			return slicesEqual(sliceA, sliceB)
+			return slicesEqual(sliceA, sliceB, numeric)
 		}
 	}
 
-	// For structs, we need deep comparison using DiffStructs
+	// For structs, we need deep comparison using Equal, which considers
+	// structs that cannot be compared different
 	if isStruct(a) && isStruct(b) {
-		diff, err := DiffStructs(a, b)
-		if err != nil {
-			// If there's an error comparing structs, consider them different
-			return false
+		if numeric {
+			return Equal(a, b, NormalizeNumbers())
+		}
+		return Equal(a, b)
+	}
+
+	// JSON numbers are compared by value, so that 1 and 1.0 are equal
+	if numA, ok := a.(json.Number); ok {
+		if numB, ok := b.(json.Number); ok {
+			return jsonNumbersEqual(numA, numB)
 		}
-		return len(diff) == 0
 	}
 
-	// For basic types, use safe comparison that handles uncomparable types
-	return safeEqual(a, b)
+	// Anything else, including typed slices, maps and arrays, is compared by
+	// reflection like struct fields are, so that uncomparable values that
+	// are deeply equal are equal
+	return directValuesEqual(reflect.ValueOf(a), reflect.ValueOf(b), numeric)
 }
 
 // mapsEqual compares two maps for deep equality
-func mapsEqual(a, b map[string]any) bool {
+func mapsEqual(a, b map[string]any, numeric bool) bool {
 	if len(a) != len(b) {
 		return false
 	}
 
 	for key, valA := range a {
 		valB, exists := b[key]
-		if !exists || !valuesEqual(valA, valB) {
+		if !exists || !valuesEqual(valA, valB, numeric) {
 			return false
 		}
 	}
@@ -135,13 +216,13 @@ func mapsEqual(a, b map[string]any) bool {
 }
 
 // slicesEqual compares two slices for deep equality
-func slicesEqual(a, b []any) bool {
+func slicesEqual(a, b []any, numeric bool) bool {
 	if len(a) != len(b) {
 		return false
 	}
 
 	for i := range a {
-		if !valuesEqual(a[i], b[i]) {
+		if !valuesEqual(a[i], b[i], numeric) {
 			return false
 		}
 	}
@@ -161,6 +242,14 @@ func isSlice(v any) bool {
 	return ok
 }
 
+// isArray checks if a value is a fixed-size array
+func isArray(v any) bool {
+	if v == nil {
+		return false
+	}
+	return reflect.ValueOf(v).Kind() == reflect.Array
+}
+
 // isStruct checks if a value is a struct
 func isStruct(v any) bool {
 	if v == nil {
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDiffMaps_SameValues(t *testing.T) {
@@ -802,3 +803,60 @@ func TestDiffMaps_MixedStructMapValues(t *testing.T) {
 		assert.Equal(t, expected, result)
 	})
 }
+
+func TestDiffMaps_TypedCollections(t *testing.T) {
+	port := 80
+	samePort := 80
+	old := map[string]any{
+		"tags":    []string{"a", "b"},
+		"limits":  map[string]int{"cpu": 2},
+		"grid":    [2][]int{{1}, {2}},
+		"nested":  []map[string]any{{"x": []string{"y"}}},
+		"port":    &port,
+		"handler": (func())(nil),
+		"bytes":   []byte("abc"),
+	}
+	new := map[string]any{
+		"tags":    []string{"a", "b"},
+		"limits":  map[string]int{"cpu": 2},
+		"grid":    [2][]int{{1}, {2}},
+		"nested":  []map[string]any{{"x": []string{"y"}}},
+		"port":    &samePort,
+		"handler": (func())(nil),
+		"bytes":   []byte("abc"),
+	}
+
+	patch, err := DiffMaps(old, new)
+	require.NoError(t, err)
+	assert.Empty(t, patch)
+
+	new["tags"] = []string{"a", "c"}
+	new["limits"] = map[string]int{"cpu": 4}
+	new["bytes"] = []byte("abd")
+	patch, err = DiffMaps(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"tags":   []string{"a", "c"},
+		"limits": map[string]int{"cpu": 4},
+		"bytes":  []byte("abd"),
+	}, patch)
+}
+
+func TestDiffMaps_TypedCollectionsOfDifferentTypes(t *testing.T) {
+	old := map[string]any{"tags": []string{"a"}}
+	new := map[string]any{"tags": []any{"a"}}
+
+	patch, err := DiffMaps(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"tags": []any{"a"}}, patch)
+}
+
+func TestDiff_TypedSlices(t *testing.T) {
+	patch, err := Diff([]string{"a"}, []string{"a"})
+	require.NoError(t, err)
+	assert.Nil(t, patch)
+
+	patch, err = Diff([]string{"a"}, []string{"b"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"b"}, patch)
+}
@@ -1,7 +1,11 @@
 package structdiff
 
 import (
+	"encoding/json"
+	"fmt"
 	"reflect"
+	"sort"
+	"strconv"
 	"time"
 )
 
@@ -22,14 +26,24 @@ import (
 //
 // The resulting patch can be applied using ApplyToStruct or ApplyToMap.
 // Returns (result, nil) on success, or (nil, error) if an error occurs during diffing.
-func DiffStructs(old, new any) (map[string]any, error) {
-	return diffStructValues(reflect.ValueOf(old), reflect.ValueOf(new))
+func DiffStructs(old, new any, opts ...Option) (map[string]any, error) {
+	b := &patchBuilder{}
+	if err := newDiffer(newOptions(opts), visitorSink{b}, false).diffStructValues(reflect.ValueOf(old), reflect.ValueOf(new)); err != nil {
+		return nil, err
+	}
+	result, _ := b.root.(map[string]any)
+	return result, nil
 }
 
-func diffStructValues(oldVal, newVal reflect.Value) (map[string]any, error) {
-	// Handle nil cases - return empty map for nil vs nil, fallback for others
+// diffStructValues reports the differences between two struct values, or
+// pointers to them, at the current path. It implements DiffStructs.
+func (d *differ) diffStructValues(oldVal, newVal reflect.Value) error {
+	// Handle nil cases - empty result for nil vs nil, fallback for others
 	if !oldVal.IsValid() && !newVal.IsValid() {
-		return map[string]any{}, nil
+		if d.enter() {
+			d.leave()
+		}
+		return nil
 	}
 	if !oldVal.IsValid() || !newVal.IsValid() {
 		// For mixed nil cases, fall back to map-based approach
@@ -40,24 +54,31 @@ func diffStructValues(oldVal, newVal reflect.Value) (map[string]any, error) {
 		if newVal.IsValid() {
 			newInterface = newVal.Interface()
 		}
-		oldMap := ToMap(oldInterface)
-		newMap := ToMap(newInterface)
-		return DiffMaps(oldMap, newMap)
+		return d.diffViaMaps(oldInterface, newInterface)
 	}
 
 	// Handle pointers
 	if oldVal.Kind() == reflect.Pointer {
 		if oldVal.IsNil() && newVal.Kind() == reflect.Pointer && newVal.IsNil() {
-			return map[string]any{}, nil
+			if d.enter() {
+				d.leave()
+			}
+			return nil
 		}
 		if oldVal.IsNil() {
-			return diffStructValues(reflect.Value{}, newVal)
+			return d.diffStructValues(reflect.Value{}, newVal)
 		}
 		oldVal = oldVal.Elem()
 	}
 	if newVal.Kind() == reflect.Pointer {
 		if newVal.IsNil() {
-			return diffStructValues(oldVal, reflect.Value{})
+			return d.diffStructValues(oldVal, reflect.Value{})
+		}
+		// Values from new are emitted with toMapValue, so mark the root as
+		// being traversed for back-pointers to it to be seen as cycles
+		if !d.noValues {
+			d.o.enterPointer(newVal)
+			defer d.o.leavePointer(newVal)
 		}
 		newVal = newVal.Elem()
 	}
@@ -65,40 +86,55 @@ func diffStructValues(oldVal, newVal reflect.Value) (map[string]any, error) {
 	// Both must be structs for struct diffing
 	if oldVal.Kind() != reflect.Struct || newVal.Kind() != reflect.Struct {
 		// Not structs, fall back to map-based approach
-		oldMap := ToMap(oldVal.Interface())
-		newMap := ToMap(newVal.Interface())
-		return DiffMaps(oldMap, newMap)
+		return d.diffViaMaps(oldVal.Interface(), newVal.Interface())
 	}
 
 	// Special case: time.Time
 	if oldVal.Type() == reflect.TypeOf(time.Time{}) && newVal.Type() == reflect.TypeOf(time.Time{}) {
-		if oldVal.Interface().(time.Time).Equal(newVal.Interface().(time.Time)) {
-			return map[string]any{}, nil
+		if !d.enter() {
+			return nil
+		}
+		defer d.leave()
+		if !timeValuesEqual(oldVal, newVal) {
+			d.emit("", true, true, oldVal.Interface(), newVal.Interface(), newVal.Type())
 		}
-		return map[string]any{"": newVal.Interface()}, nil
+		return nil
 	}
 
 	// Different struct types - fall back to map-based approach
 	if oldVal.Type() != newVal.Type() {
-		oldMap := ToMap(oldVal.Interface())
-		newMap := ToMap(newVal.Interface())
-		return DiffMaps(oldMap, newMap)
+		return d.diffViaMaps(oldVal.Interface(), newVal.Interface())
 	}
 
-	return diffSameTypeStructs(oldVal, newVal)
+	return d.diffSameTypeStructs(oldVal, newVal)
 }
 
-func diffSameTypeStructs(oldVal, newVal reflect.Value) (map[string]any, error) {
-	result := make(map[string]any)
-	oldType := oldVal.Type()
-	newType := newVal.Type()
+// diffViaMaps diffs two values by converting them with ToMap and using DiffMaps.
+func (d *differ) diffViaMaps(old, new any) error {
+	d.syncPath()
+	oldMap, err := toMap(old, d.o)
+	if err != nil {
+		return err
+	}
+	newMap, err := toMap(new, d.o)
+	if err != nil {
+		return err
+	}
+	return d.diffMaps(oldMap, newMap)
+}
 
-	// Track fields seen in new struct
-	seenInNew := make(map[string]bool)
+func (d *differ) diffSameTypeStructs(oldVal, newVal reflect.Value) error {
+	if !d.enter() {
+		return nil
+	}
+	defer d.leave()
 
-	// Process fields in new struct
+	structType := newVal.Type()
 	for i := 0; i < newVal.NumField(); i++ {
-		field := newType.Field(i)
+		if d.halted() {
+			return nil
+		}
+		field := structType.Field(i)
 		if !field.IsExported() {
 			continue
 		}
@@ -108,110 +144,250 @@ func diffSameTypeStructs(oldVal, newVal reflect.Value) (map[string]any, error) {
 			continue
 		}
 		name := parseName(tag, field.Name)
-		seenInNew[name] = true
 
+		oldFieldVal := oldVal.Field(i)
 		newFieldVal := newVal.Field(i)
+		if isRedactedField(field) || d.redactedAt(name) {
+			if err := d.diffRedacted(name, oldFieldVal, newFieldVal, directValuesEqual(oldFieldVal, newFieldVal, d.o.normalizeNumbers), field.Type); err != nil {
+				return err
+			}
+			continue
+		}
+
+		oldIsNilPointer := oldFieldVal.Kind() == reflect.Pointer && oldFieldVal.IsNil()
 
 		// Handle nil pointers in new struct (omit them)
 		if newFieldVal.Kind() == reflect.Pointer && newFieldVal.IsNil() {
-			// Check if old had this field
-			oldFieldVal, oldExists := getFieldByName(oldVal, oldType, name)
-			if oldExists && !(oldFieldVal.Kind() == reflect.Pointer && oldFieldVal.IsNil()) {
+			if !oldIsNilPointer {
 				// Old had non-nil value, new has nil pointer -> deletion
-				result[name] = nil
+				old, err := d.oldValue(oldFieldVal)
+				if err != nil {
+					return err
+				}
+				d.emit(name, true, false, old, nil, field.Type)
 			}
 			continue
 		}
 
-		// Find corresponding field in old struct
-		oldFieldVal, oldExists := getFieldByName(oldVal, oldType, name)
-
-		if !oldExists {
-			// Field only exists in new
-			result[name] = toMapValue(newFieldVal)
-		} else if oldFieldVal.Kind() == reflect.Pointer && oldFieldVal.IsNil() {
+		if oldIsNilPointer {
 			// Old had nil pointer, new has value
-			result[name] = toMapValue(newFieldVal)
-		} else {
-			// Both have the field, check if values differ
-			if !directValuesEqual(oldFieldVal, newFieldVal) {
-				oldInterface := oldFieldVal.Interface()
-				newInterface := newFieldVal.Interface()
-
-				// Special case: time.Time should be handled directly, not through Diff
-				if oldFieldVal.Type() == reflect.TypeOf(time.Time{}) && newFieldVal.Type() == reflect.TypeOf(time.Time{}) {
-					result[name] = toMapValue(newFieldVal)
-				} else if (isStruct(oldInterface) || isMap(oldInterface)) && (isStruct(newInterface) || isMap(newInterface)) {
-					// Use unified Diff function for any combination of structs and maps (except time.Time)
-					diff, err := Diff(oldInterface, newInterface)
-					if err != nil {
-						return nil, err
-					}
-					if diff != nil {
-						diffMap, ok := diff.(map[string]any)
-						if ok && len(diffMap) > 0 {
-							result[name] = diffMap
-						}
-					}
-					if diffMap, ok := diff.(map[string]any); ok && len(diffMap) > 0 {
-						result[name] = diff
-					}
-				} else {
-					// For other types (primitives, slices, etc.) - include new value
-					result[name] = toMapValue(newFieldVal)
-				}
+			val, err := d.newValue(newFieldVal)
+			if err != nil {
+				return err
+			}
+			d.emit(name, false, true, nil, val, field.Type)
+		} else if !directValuesEqual(oldFieldVal, newFieldVal, d.o.normalizeNumbers) {
+			// Both have values and they differ
+			d.push(name)
+			err := d.diffChangedValues(oldFieldVal, newFieldVal, field.Type)
+			d.pop()
+			if err != nil {
+				return err
 			}
 		}
 	}
 
-	// Process fields that exist only in old struct (deletions)
-	for i := 0; i < oldVal.NumField(); i++ {
-		field := oldType.Field(i)
-		if !field.IsExported() {
-			continue
-		}
+	return nil
+}
 
-		tag := field.Tag.Get("json")
-		if tag == "-" {
-			continue
+// diffChangedValues reports the differences between two values of the same
+// static type that are known to differ, at the current path. Structs, pointers
+// to structs and maps are diffed recursively, typed maps key by key and, with
+// DiffArraysByIndex, arrays index by index; anything else is reported whole.
+// Interface values holding the same dynamic type are diffed by their dynamic
+// values.
+func (d *differ) diffChangedValues(oldVal, newVal reflect.Value, typ reflect.Type) error {
+	if oldVal.Kind() == reflect.Interface && !oldVal.IsNil() && !newVal.IsNil() &&
+		oldVal.Elem().Type() == newVal.Elem().Type() {
+		return d.diffChangedValues(oldVal.Elem(), newVal.Elem(), typ)
+	}
+
+	// Pointers to structs are diffed through their pointees, so that the
+	// patch can be applied onto the existing value. A pointer already being
+	// traversed is part of a cycle and is reported whole, for toMapValue to
+	// handle according to OnCycle.
+	if oldVal.Kind() == reflect.Pointer && !oldVal.IsNil() && !newVal.IsNil() &&
+		oldVal.Elem().Kind() == reflect.Struct && oldVal.Type().Elem() != reflect.TypeOf(time.Time{}) &&
+		!d.o.visiting(newVal) {
+		d.o.enterPointer(newVal)
+		defer d.o.leavePointer(newVal)
+		return d.diffStructValues(oldVal.Elem(), newVal.Elem())
+	}
+
+	switch {
+	case oldVal.Type() == reflect.TypeOf(time.Time{}):
+		// Special case: time.Time should be handled directly, not through Diff
+	case oldVal.Kind() == reflect.Struct && newVal.Kind() == reflect.Struct:
+		// Same-typed structs are diffed directly, without converting them to
+		// interfaces
+		return d.diffStructValues(oldVal, newVal)
+	case isStructOrMapValue(oldVal) && isStructOrMapValue(newVal):
+		// Use unified Diff function for any combination of structs and maps (except time.Time)
+		return d.diffAny(oldVal.Interface(), newVal.Interface())
+	case oldVal.Kind() == reflect.Map && !oldVal.IsNil() && !newVal.IsNil():
+		// Typed maps (map[int]Foo, map[string]Bar, ...) are diffed key by key
+		return d.diffMapValues(oldVal, newVal)
+	case d.o.arrayIndexDiff && oldVal.Kind() == reflect.Array:
+		// Arrays never change length, so report only the changed indices
+		return d.diffArrayValues(oldVal, newVal)
+	}
+
+	// For other types (primitives, slices, etc.) - report the new value
+	old, err := d.oldValue(oldVal)
+	if err != nil {
+		return err
+	}
+	val, err := d.newValue(newVal)
+	if err != nil {
+		return err
+	}
+	d.change(!isNilValue(oldVal), !isNilValue(newVal), old, val, typ)
+	return nil
+}
+
+// isStructOrMapValue reports whether v holds a struct or a map[string]any,
+// directly or in an interface.
+func isStructOrMapValue(v reflect.Value) bool {
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return false
 		}
-		name := parseName(tag, field.Name)
+		v = v.Elem()
+	}
+	return v.Kind() == reflect.Struct || v.Type() == reflect.TypeOf(map[string]any{})
+}
 
-		if !seenInNew[name] {
-			// Field exists only in old - deletion
-			oldFieldVal := oldVal.Field(i)
-			if !(oldFieldVal.Kind() == reflect.Pointer && oldFieldVal.IsNil()) {
-				result[name] = nil
+// diffMapValues reports the differences between two maps of the same type,
+// key by key. Keys are stringified with fmt.Sprint, as in ToMap. Changed
+// values are diffed as by diffChangedValues.
+func (d *differ) diffMapValues(oldVal, newVal reflect.Value) error {
+	if !d.enter() {
+		return nil
+	}
+	defer d.leave()
+
+	elemType := newVal.Type().Elem()
+	for _, key := range d.mapKeyOrder(oldVal, newVal) {
+		if d.halted() {
+			return nil
+		}
+		name := fmt.Sprint(key.Interface())
+		oldElem := oldVal.MapIndex(key)
+		newElem := newVal.MapIndex(key)
+
+		switch {
+		case d.redactedAt(name):
+			equal := oldElem.IsValid() && newElem.IsValid() && directValuesEqual(oldElem, newElem, d.o.normalizeNumbers)
+			if err := d.diffRedacted(name, oldElem, newElem, equal, elemType); err != nil {
+				return err
+			}
+		case !newElem.IsValid():
+			// Key only exists in old - deletion
+			old, err := d.oldValue(oldElem)
+			if err != nil {
+				return err
+			}
+			d.emit(name, true, false, old, nil, elemType)
+		case !oldElem.IsValid():
+			// Key only exists in new
+			val, err := d.newValue(newElem)
+			if err != nil {
+				return err
+			}
+			d.emit(name, false, true, nil, val, elemType)
+		case directValuesEqual(oldElem, newElem, d.o.normalizeNumbers):
+		case isNilValue(oldElem) || isNilValue(newElem):
+			// Key in both, holding nil on one side only
+			old, err := d.oldValue(oldElem)
+			if err != nil {
+				return err
+			}
+			val, err := d.newValue(newElem)
+			if err != nil {
+				return err
+			}
+			d.emit(name, true, true, old, val, elemType)
+		default:
+			d.push(name)
+			err := d.diffChangedValues(oldElem, newElem, elemType)
+			d.pop()
+			if err != nil {
+				return err
 			}
 		}
 	}
 
-	return result, nil
+	return nil
 }
 
-// getFieldByName finds a field in a struct by its JSON name
-func getFieldByName(structVal reflect.Value, structType reflect.Type, name string) (reflect.Value, bool) {
-	for i := 0; i < structVal.NumField(); i++ {
-		field := structType.Field(i)
-		if !field.IsExported() {
+// mapKeyOrder returns the keys of two typed maps in the order they are
+// visited: keys of new followed by keys only in old, or all keys sorted by
+// their string form when a deterministic order is required.
+func (d *differ) mapKeyOrder(oldVal, newVal reflect.Value) []reflect.Value {
+	keys := newVal.MapKeys()
+	for _, key := range oldVal.MapKeys() {
+		if !newVal.MapIndex(key).IsValid() {
+			keys = append(keys, key)
+		}
+	}
+	if d.o.sortKeys {
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+	}
+	return keys
+}
+
+// diffArrayValues reports the differences between two arrays of the same type
+// element by element, keyed by the decimal index of each changed element.
+// Changed elements are diffed as by diffChangedValues.
+func (d *differ) diffArrayValues(oldVal, newVal reflect.Value) error {
+	if !d.enter() {
+		return nil
+	}
+	defer d.leave()
+
+	elemType := newVal.Type().Elem()
+	for i := 0; i < newVal.Len(); i++ {
+		if d.halted() {
+			return nil
+		}
+		oldElem := oldVal.Index(i)
+		newElem := newVal.Index(i)
+		if directValuesEqual(oldElem, newElem, d.o.normalizeNumbers) {
 			continue
 		}
 
-		tag := field.Tag.Get("json")
-		if tag == "-" {
+		key := strconv.Itoa(i)
+		if d.redactedAt(key) {
+			if err := d.diffRedacted(key, oldElem, newElem, false, elemType); err != nil {
+				return err
+			}
 			continue
 		}
-		fieldName := parseName(tag, field.Name)
 
-		if fieldName == name {
-			return structVal.Field(i), true
+		d.push(key)
+		err := d.diffChangedValues(oldElem, newElem, elemType)
+		d.pop()
+		if err != nil {
+			return err
 		}
 	}
-	return reflect.Value{}, false
+	return nil
 }
 
-// directValuesEqual compares two reflect.Values directly without conversion to interface{}
-func directValuesEqual(a, b reflect.Value) bool {
+// directValuesEqual compares two reflect.Values directly without conversion to
+// interface{}. With numeric set, values of different types are compared as by
+// NormalizeNumbers.
+func directValuesEqual(a, b reflect.Value, numeric bool) bool {
+	return deepValuesEqual(a, b, numeric, nil)
+}
+
+// deepValuesEqual implements directValuesEqual. visited records the pointer
+// pairs already being compared, so that cyclic values terminate: a pair seen
+// again is assumed equal, as reflect.DeepEqual does. It is allocated only once
+// a pointer is encountered.
+func deepValuesEqual(a, b reflect.Value, numeric bool, visited map[visitPair]bool) bool {
 	if !a.IsValid() && !b.IsValid() {
 		return true
 	}
@@ -220,9 +396,23 @@ func directValuesEqual(a, b reflect.Value) bool {
 	}
 
 	if a.Type() != b.Type() {
+		if numeric {
+			return looseValuesEqual(a, b, visited)
+		}
 		return false
 	}
 
+	// Handle interfaces: compare dynamic values, which may be uncomparable
+	if a.Kind() == reflect.Interface {
+		if a.IsNil() || b.IsNil() {
+			return a.IsNil() && b.IsNil()
+		}
+		if a.Elem().Type() != b.Elem().Type() && !numeric {
+			return false
+		}
+		return deepValuesEqual(a.Elem(), b.Elem(), numeric, visited)
+	}
+
 	// Handle pointers
 	if a.Kind() == reflect.Pointer && b.Kind() == reflect.Pointer {
 		if a.IsNil() && b.IsNil() {
@@ -231,14 +421,25 @@ func directValuesEqual(a, b reflect.Value) bool {
 		if a.IsNil() || b.IsNil() {
 			return false
 		}
-		return directValuesEqual(a.Elem(), b.Elem())
+		if a.Pointer() == b.Pointer() {
+			return true
+		}
+		pair := visitPair{a.Pointer(), b.Pointer(), a.Type()}
+		if visited[pair] {
+			return true
+		}
+		if visited == nil {
+			visited = make(map[visitPair]bool)
+		}
+		visited[pair] = true
+		return deepValuesEqual(a.Elem(), b.Elem(), numeric, visited)
 	}
 
 	// Handle structs
 	if a.Kind() == reflect.Struct {
 		// Special case: time.Time
 		if a.Type() == reflect.TypeOf(time.Time{}) {
-			return a.Interface().(time.Time).Equal(b.Interface().(time.Time))
+			return timeValuesEqual(a, b)
 		}
 
 		// For other structs, compare field by field
@@ -247,7 +448,7 @@ func directValuesEqual(a, b reflect.Value) bool {
 		}
 
 		for i := 0; i < a.NumField(); i++ {
-			if !directValuesEqual(a.Field(i), b.Field(i)) {
+			if !deepValuesEqual(a.Field(i), b.Field(i), numeric, visited) {
 				return false
 			}
 		}
@@ -270,7 +471,7 @@ func directValuesEqual(a, b reflect.Value) bool {
 		}
 
 		for i := 0; i < a.Len(); i++ {
-			if !directValuesEqual(a.Index(i), b.Index(i)) {
+			if !deepValuesEqual(a.Index(i), b.Index(i), numeric, visited) {
 				return false
 			}
 		}
@@ -290,16 +491,63 @@ func directValuesEqual(a, b reflect.Value) bool {
 			return false
 		}
 
+		// Plain map[string]any values are ranged over directly, which
+		// unlike MapKeys and MapIndex does not allocate
+		if a.Type() == reflect.TypeOf(map[string]any{}) && a.CanInterface() && b.CanInterface() {
+			mapB := b.Interface().(map[string]any)
+			for key, aVal := range a.Interface().(map[string]any) {
+				bVal, ok := mapB[key]
+				if !ok || !deepValuesEqual(reflect.ValueOf(aVal), reflect.ValueOf(bVal), numeric, visited) {
+					return false
+				}
+			}
+			return true
+		}
+
 		for _, key := range a.MapKeys() {
 			aVal := a.MapIndex(key)
 			bVal := b.MapIndex(key)
-			if !bVal.IsValid() || !directValuesEqual(aVal, bVal) {
+			if !bVal.IsValid() || !deepValuesEqual(aVal, bVal, numeric, visited) {
 				return false
 			}
 		}
 		return true
 	}
 
-	// For basic types, compare interfaces
+	// For basic types, compare by kind, which unlike comparing interfaces
+	// does not allocate
+	switch a.Kind() {
+	case reflect.Bool:
+		return a.Bool() == b.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return a.Int() == b.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return a.Uint() == b.Uint()
+	case reflect.Float32, reflect.Float64:
+		return a.Float() == b.Float()
+	case reflect.Complex64, reflect.Complex128:
+		return a.Complex() == b.Complex()
+	case reflect.String:
+		if a.Type() == jsonNumberType {
+			return jsonNumbersEqual(json.Number(a.String()), json.Number(b.String()))
+		}
+		return a.String() == b.String()
+	case reflect.Chan, reflect.UnsafePointer:
+		return a.Pointer() == b.Pointer()
+	case reflect.Func:
+		// Functions are uncomparable; like reflect.DeepEqual, only nil
+		// functions are equal
+		return a.IsNil() && b.IsNil()
+	}
 	return a.Interface() == b.Interface()
 }
+
+// timeValuesEqual compares two time.Time values with time.Time.Equal. Times
+// that are identical field by field are equal without converting them to
+// interfaces, which would allocate.
+func timeValuesEqual(a, b reflect.Value) bool {
+	if a.Equal(b) {
+		return true
+	}
+	return a.Interface().(time.Time).Equal(b.Interface().(time.Time))
+}
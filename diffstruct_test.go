@@ -5,6 +5,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestDiff_ComprehensiveTests(t *testing.T) {
@@ -464,12 +465,10 @@ func TestDiffStructs_Integration(t *testing.T) {
 		}
 
 		// When applying a map patch to an `any` field containing a struct,
-		// ApplyToStruct replaces the struct with the map (since it's an interface{} field)
+		// ApplyToStruct patches the struct and keeps its concrete type
 		expectedAfterPatch := UserWithMixed{
-			Name: "Jane",
-			Address: map[string]any{
-				"street": "456 Oak Ave",
-			},
+			Name:    "Jane",
+			Address: Address{Street: "456 Oak Ave", City: "NYC"},
 		}
 
 		// Create a diff that changes name and address.street
@@ -488,3 +487,203 @@ func TestDiffStructs_Integration(t *testing.T) {
 		assert.Equal(t, expectedAfterPatch, result)
 	})
 }
+
+func TestDiffStructs_Arrays(t *testing.T) {
+	type Point struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	}
+	type WithArrays struct {
+		Coords [3]float64 `json:"coords"`
+		Points [2]Point   `json:"points"`
+	}
+
+	old := WithArrays{Coords: [3]float64{1, 2, 3}, Points: [2]Point{{1, 1}, {2, 2}}}
+	new := WithArrays{Coords: [3]float64{1, 5, 3}, Points: [2]Point{{1, 1}, {2, 3}}}
+
+	t.Run("whole array by default", func(t *testing.T) {
+		diff, err := DiffStructs(old, new)
+		require.NoError(t, err)
+		assert.Equal(t, []any{1.0, 5.0, 3.0}, diff["coords"])
+	})
+
+	t.Run("per-index changes", func(t *testing.T) {
+		diff, err := DiffStructs(old, new, DiffArraysByIndex())
+		require.NoError(t, err)
+		expected := map[string]any{
+			"coords": map[string]any{"1": 5.0},
+			"points": map[string]any{"1": map[string]any{"y": 3}},
+		}
+		assert.Equal(t, expected, diff)
+
+		result := old
+		require.NoError(t, ApplyToStruct(&result, diff))
+		assert.Equal(t, new, result)
+	})
+
+	t.Run("unchanged arrays omitted", func(t *testing.T) {
+		diff, err := DiffStructs(old, old, DiffArraysByIndex())
+		require.NoError(t, err)
+		assert.Empty(t, diff)
+	})
+
+	t.Run("maps round-trip", func(t *testing.T) {
+		oldMap := map[string]any{"coords": [3]int{1, 2, 3}}
+		newMap := map[string]any{"coords": [3]int{1, 2, 4}}
+
+		diff, err := DiffMaps(oldMap, newMap, DiffArraysByIndex())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"coords": map[string]any{"2": 4}}, diff)
+		assert.Equal(t, newMap, ApplyToMap(oldMap, diff))
+	})
+}
+
+func TestDiffStructs_TypedMaps(t *testing.T) {
+	type Server struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	type Config struct {
+		Servers map[string]Server `json:"servers"`
+		Weights map[int]float64   `json:"weights"`
+		Groups  map[string][]int  `json:"groups"`
+	}
+
+	old := Config{
+		Servers: map[string]Server{"a": {"a.example.com", 80}, "b": {"b.example.com", 80}},
+		Weights: map[int]float64{1: 0.5, 2: 0.5},
+		Groups:  map[string][]int{"x": {1, 2}},
+	}
+	new := Config{
+		Servers: map[string]Server{"a": {"a.example.com", 81}, "c": {"c.example.com", 80}},
+		Weights: map[int]float64{1: 0.5, 2: 0.25, 3: 0.25},
+		Groups:  map[string][]int{"x": {1, 2}},
+	}
+
+	diff, err := DiffStructs(old, new)
+	require.NoError(t, err)
+
+	expected := map[string]any{
+		"servers": map[string]any{
+			"a": map[string]any{"port": 81},
+			"b": nil,
+			"c": map[string]any{"host": "c.example.com", "port": 80},
+		},
+		"weights": map[string]any{"2": 0.25, "3": 0.25},
+	}
+	assert.Equal(t, expected, diff)
+
+	t.Run("nil to non-nil map is included whole", func(t *testing.T) {
+		diff, err := DiffStructs(Config{}, Config{Weights: map[int]float64{1: 1}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"weights": map[string]any{"1": 1.0}}, diff)
+	})
+
+	t.Run("non-nil to nil map is a deletion", func(t *testing.T) {
+		diff, err := DiffStructs(Config{Weights: map[int]float64{1: 1}}, Config{})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"weights": nil}, diff)
+	})
+}
+
+func TestDiffStructs_InterfaceFields(t *testing.T) {
+	type Server struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	type Envelope struct {
+		Payload any `json:"payload"`
+	}
+
+	t.Run("same dynamic struct type diffed recursively", func(t *testing.T) {
+		old := Envelope{Payload: Server{Host: "a", Port: 80}}
+		new := Envelope{Payload: Server{Host: "a", Port: 81}}
+
+		diff, err := DiffStructs(old, new)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"payload": map[string]any{"port": 81}}, diff)
+
+		require.NoError(t, ApplyToStruct(&old, diff))
+		assert.Equal(t, new, old)
+	})
+
+	t.Run("same dynamic typed map diffed key by key", func(t *testing.T) {
+		old := Envelope{Payload: map[int]Server{1: {"a", 80}, 2: {"b", 80}}}
+		new := Envelope{Payload: map[int]Server{1: {"a", 81}, 3: {"c", 80}}}
+
+		diff, err := DiffStructs(old, new)
+		require.NoError(t, err)
+		expected := map[string]any{"payload": map[string]any{
+			"1": map[string]any{"port": 81},
+			"2": nil,
+			"3": map[string]any{"host": "c", "port": 80},
+		}}
+		assert.Equal(t, expected, diff)
+
+		require.NoError(t, ApplyToStruct(&old, diff))
+		assert.Equal(t, new, old)
+	})
+
+	t.Run("uncomparable dynamic values do not panic", func(t *testing.T) {
+		old := Envelope{Payload: []int{1, 2}}
+		new := Envelope{Payload: []int{1, 2}}
+
+		diff, err := DiffStructs(old, new)
+		require.NoError(t, err)
+		assert.Empty(t, diff)
+	})
+
+	t.Run("different dynamic types replaced", func(t *testing.T) {
+		old := Envelope{Payload: 42}
+		new := Envelope{Payload: "forty-two"}
+
+		diff, err := DiffStructs(old, new)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"payload": "forty-two"}, diff)
+
+		require.NoError(t, ApplyToStruct(&old, diff))
+		assert.Equal(t, new, old)
+	})
+
+	t.Run("pointer dynamic value round-trips", func(t *testing.T) {
+		old := Envelope{Payload: &Server{Host: "a", Port: 80}}
+		new := Envelope{Payload: &Server{Host: "b", Port: 80}}
+
+		diff, err := DiffStructs(old, new)
+		require.NoError(t, err)
+
+		require.NoError(t, ApplyToStruct(&old, diff))
+		assert.Equal(t, new, old)
+	})
+
+	t.Run("sparse patch onto dynamic pointer keeps other fields", func(t *testing.T) {
+		shared := &Server{Host: "a", Port: 80}
+		target := Envelope{Payload: shared}
+
+		require.NoError(t, ApplyToStruct(&target, map[string]any{"payload": map[string]any{"port": 81}}))
+		assert.Equal(t, Envelope{Payload: &Server{Host: "a", Port: 81}}, target)
+		assert.Equal(t, &Server{Host: "a", Port: 80}, shared, "original pointee must not be mutated")
+	})
+
+	t.Run("strict options apply within dynamic values", func(t *testing.T) {
+		target := Envelope{Payload: Server{Host: "a", Port: 80}}
+
+		err := ApplyToStruct(&target, map[string]any{"payload": map[string]any{"port": "81"}}, NoWeakTyping())
+		assert.Error(t, err)
+
+		err = ApplyToStruct(&target, map[string]any{"payload": map[string]any{"email": "x"}}, DisallowUnknownFields())
+		assert.Error(t, err)
+		assert.Equal(t, Envelope{Payload: Server{Host: "a", Port: 80}}, target)
+	})
+
+	t.Run("dynamic struct changed to map round-trips", func(t *testing.T) {
+		old := Envelope{Payload: Server{Host: "h", Port: 80}}
+		new := Envelope{Payload: map[string]any{"port": 81}}
+
+		diff, err := DiffStructs(old, new)
+		require.NoError(t, err)
+
+		require.NoError(t, ApplyToStruct(&old, diff))
+		assert.Equal(t, new, old)
+	})
+}
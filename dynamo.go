@@ -0,0 +1,259 @@
+package structdiff
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// DynamoExpression is a DynamoDB update expression with the placeholders it
+// uses, ready to be passed as the UpdateExpression, ExpressionAttributeNames
+// and ExpressionAttributeValues of an UpdateItem request.
+type DynamoExpression struct {
+	// Expression is the update expression, such as
+	// "SET #n0.#n1 = :v0 REMOVE #n2".
+	Expression string
+
+	// Names maps each name placeholder to the attribute name it stands for.
+	Names map[string]string
+
+	// Values maps each value placeholder to its value, taken from new as it
+	// is, for the caller to marshal into attribute values. It is nil if the
+	// expression has no values, as DynamoDB rejects an empty map.
+	Values map[string]any
+}
+
+// DynamoUpdate computes the differences between old and new, as DiffStructs
+// does, and returns them as a DynamoDB update expression:
+//
+//	SET #n0.#n1 = :v0, #n2 = :v1 REMOVE #n3
+//
+// Changed values, including those within nested structs and maps, are set by
+// their document path, and removed values, such as pointers that became nil,
+// are removed. Since DynamoDB cannot update a path below an attribute that
+// does not exist, a nested value whose parent was nil or missing in old is
+// set whole, and updates below it are left out, as DynamoDB rejects
+// overlapping document paths. Every attribute name is a placeholder, so reserved words need no
+// care, and a name used several times has one placeholder. Field names come
+// from "dynamodbav" tags, or the tag chosen with NameTag, falling back to
+// JSON names.
+//
+// With ArrayUpdates, a slice that only grew at its end is updated with
+// list_append, and one that only lost some of its elements by removing them
+// by index.
+//
+// Returns an empty expression if old and new are equal.
+func DynamoUpdate(old, new any, opts ...Option) (DynamoExpression, error) {
+	o := newOptions(opts)
+	tag := o.nameTag
+	if tag == "" {
+		tag = "dynamodbav"
+	}
+
+	changes, err := Changes(old, new, opts...)
+	if err != nil {
+		return DynamoExpression{}, err
+	}
+
+	root := reflect.TypeOf(new)
+	if root == nil {
+		root = reflect.TypeOf(old)
+	}
+
+	// Collect the updates first, so that those below a value updated whole
+	// can be left out before placeholders are assigned
+	var updates []dynamoUpdate
+	seen := make(map[string]bool)
+
+	for _, change := range changes {
+		path := change.Path
+		for i, key := range path {
+			if key == "" {
+				path = path[:i]
+				break
+			}
+		}
+
+		// Stop at the first parent that does not exist in old, noting which
+		// keys on the way index lists
+		var lists []bool
+		for i := range path {
+			container, err := Get(old, formatPointer(path[:i]))
+			if i > 0 && (err != nil || isNilValue(reflect.ValueOf(container))) {
+				path = path[:i]
+				break
+			}
+			kind := reflect.ValueOf(derefValue(container)).Kind()
+			lists = append(lists, kind == reflect.Slice || kind == reflect.Array)
+		}
+
+		if len(path) == 0 {
+			return DynamoExpression{}, fmt.Errorf("cannot express change at %q as an attribute update", formatPath(change.Path))
+		}
+
+		names, ok := updatePath(root, path, tag)
+		if !ok {
+			continue
+		}
+		key := formatPointer(names)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		update := dynamoUpdate{names: names, lists: lists}
+
+		if change.Kind == Removed && len(path) == len(change.Path) {
+			update.remove = true
+			updates = append(updates, update)
+			continue
+		}
+		newVal, err := Get(new, formatPointer(path))
+		if err != nil {
+			return DynamoExpression{}, err
+		}
+		if isNilValue(reflect.ValueOf(newVal)) {
+			update.remove = true
+			updates = append(updates, update)
+			continue
+		}
+
+		update.value = derefValue(newVal)
+		if o.arrayUpdates && len(path) == len(change.Path) {
+			oldVal, err := Get(old, formatPointer(path))
+			if err == nil {
+				if appended, ok := sliceAppended(oldVal, newVal); ok {
+					update.value, update.appended = appended, true
+				} else if indices, ok := sliceRemovedIndices(oldVal, newVal); ok {
+					update.value, update.removedIndices = nil, indices
+				}
+			}
+		}
+		updates = append(updates, update)
+	}
+
+	b := &dynamoBuilder{names: make(map[string]string)}
+	var sets, removes []string
+	for _, update := range updates {
+		if coveredUpdate(updates, update.names) {
+			continue
+		}
+		attr := b.path(update.names, update.lists)
+		switch {
+		case update.remove:
+			removes = append(removes, attr)
+		case update.appended:
+			sets = append(sets, attr+" = list_append("+attr+", "+b.value(update.value)+")")
+		case update.removedIndices != nil:
+			for _, i := range update.removedIndices {
+				removes = append(removes, attr+"["+strconv.Itoa(i)+"]")
+			}
+		default:
+			sets = append(sets, attr+" = "+b.value(update.value))
+		}
+	}
+
+	var clauses []string
+	if len(sets) > 0 {
+		clauses = append(clauses, "SET "+strings.Join(sets, ", "))
+	}
+	if len(removes) > 0 {
+		clauses = append(clauses, "REMOVE "+strings.Join(removes, ", "))
+	}
+	if len(clauses) == 0 {
+		return DynamoExpression{}, nil
+	}
+	return DynamoExpression{Expression: strings.Join(clauses, " "), Names: b.exprNames, Values: b.values}, nil
+}
+
+// dynamoUpdate is an update of the value at a document path, made by
+// DynamoUpdate: the value is removed, set, appended to or has the elements at
+// removedIndices removed.
+type dynamoUpdate struct {
+	names          []string
+	lists          []bool
+	remove         bool
+	appended       bool
+	removedIndices []int
+	value          any
+}
+
+// coveredUpdate reports whether names lies below the path of one of updates,
+// since DynamoDB rejects updates of overlapping document paths.
+func coveredUpdate(updates []dynamoUpdate, names []string) bool {
+	for _, u := range updates {
+		if len(u.names) < len(names) && slices.Equal(u.names, names[:len(u.names)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// dynamoBuilder assigns the placeholders of a DynamoExpression.
+type dynamoBuilder struct {
+	names     map[string]string
+	exprNames map[string]string
+	values    map[string]any
+}
+
+// path returns the document path for names, with a placeholder for each
+// attribute name, and list indices, marked in lists, written as [n].
+func (b *dynamoBuilder) path(names []string, lists []bool) string {
+	var s strings.Builder
+	for i, name := range names {
+		if i < len(lists) && lists[i] {
+			s.WriteString("[" + name + "]")
+			continue
+		}
+		if i > 0 {
+			s.WriteByte('.')
+		}
+		s.WriteString(b.name(name))
+	}
+	return s.String()
+}
+
+// name returns the placeholder for an attribute name.
+func (b *dynamoBuilder) name(name string) string {
+	placeholder, ok := b.names[name]
+	if !ok {
+		placeholder = "#n" + strconv.Itoa(len(b.names))
+		b.names[name] = placeholder
+		if b.exprNames == nil {
+			b.exprNames = make(map[string]string)
+		}
+		b.exprNames[placeholder] = name
+	}
+	return placeholder
+}
+
+// value returns a new placeholder for v.
+func (b *dynamoBuilder) value(v any) string {
+	if b.values == nil {
+		b.values = make(map[string]any)
+	}
+	placeholder := ":v" + strconv.Itoa(len(b.values))
+	b.values[placeholder] = v
+	return placeholder
+}
+
+// sliceRemovedIndices returns the indices of the elements removed from old to
+// give new, if new is old with some of its elements removed.
+func sliceRemovedIndices(old, new any) ([]int, bool) {
+	oldVal, newVal := reflect.ValueOf(old), reflect.ValueOf(new)
+	if oldVal.Kind() != reflect.Slice || newVal.Kind() != reflect.Slice || newVal.Len() >= oldVal.Len() {
+		return nil, false
+	}
+
+	var indices []int
+	j := 0
+	for i := 0; i < oldVal.Len(); i++ {
+		if j < newVal.Len() && directValuesEqual(oldVal.Index(i), newVal.Index(j), false) {
+			j++
+			continue
+		}
+		indices = append(indices, i)
+	}
+	return indices, j == newVal.Len()
+}
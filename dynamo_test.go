@@ -0,0 +1,231 @@
+package structdiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type dynamoAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city" dynamodbav:"city_name"`
+}
+
+type dynamoItem struct {
+	ID       string            `json:"id" dynamodbav:"pk"`
+	Name     string            `json:"name"`
+	Nickname *string           `json:"nickname" dynamodbav:"nick"`
+	Home     dynamoAddress     `json:"home"`
+	Work     *dynamoAddress    `json:"work"`
+	Tags     []string          `json:"tags"`
+	Labels   map[string]string `json:"labels"`
+	Extra    map[string]any    `json:"extra"`
+	Cache    string            `json:"cache" dynamodbav:"-"`
+}
+
+func newDynamoItem() dynamoItem {
+	nick := "annie"
+	return dynamoItem{
+		ID:       "u1",
+		Name:     "Ann",
+		Nickname: &nick,
+		Home:     dynamoAddress{Street: "Main", City: "Oslo"},
+		Work:     &dynamoAddress{City: "Oslo"},
+		Tags:     []string{"a", "b", "c"},
+		Labels:   map[string]string{"team": "core"},
+	}
+}
+
+func TestDynamoUpdate(t *testing.T) {
+	old := newDynamoItem()
+	new := newDynamoItem()
+	new.Name = "Anna"
+	new.Nickname = nil
+	new.Home.City = "Bergen"
+	new.Labels = map[string]string{"team": "infra"}
+	new.Cache = "ignored"
+
+	expr, err := DynamoUpdate(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, "SET #n0 = :v0, #n2.#n3 = :v1, #n4.#n5 = :v2 REMOVE #n1", expr.Expression)
+	assert.Equal(t, map[string]string{
+		"#n0": "name",
+		"#n1": "nick",
+		"#n2": "home",
+		"#n3": "city_name",
+		"#n4": "labels",
+		"#n5": "team",
+	}, expr.Names)
+	assert.Equal(t, map[string]any{":v0": "Anna", ":v1": "Bergen", ":v2": "infra"}, expr.Values)
+}
+
+func TestDynamoUpdate_SharedNames(t *testing.T) {
+	old := map[string]any{"home": map[string]any{"city": "Oslo"}, "work": map[string]any{"city": "Oslo"}}
+	new := map[string]any{"home": map[string]any{"city": "Bergen"}, "work": map[string]any{}}
+
+	expr, err := DynamoUpdate(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, "SET #n0.#n1 = :v0 REMOVE #n2.#n1", expr.Expression)
+	assert.Equal(t, map[string]string{"#n0": "home", "#n1": "city", "#n2": "work"}, expr.Names)
+}
+
+func TestDynamoUpdate_NoChanges(t *testing.T) {
+	expr, err := DynamoUpdate(newDynamoItem(), newDynamoItem())
+	require.NoError(t, err)
+	assert.Equal(t, DynamoExpression{}, expr)
+}
+
+func TestDynamoUpdate_MissingParents(t *testing.T) {
+	old := newDynamoItem()
+	old.Work = nil
+	old.Labels = nil
+	new := newDynamoItem()
+	new.Work = &dynamoAddress{City: "Bergen"}
+	new.Labels = map[string]string{"team": "infra", "tier": "1"}
+	new.Extra = map[string]any{"a": map[string]any{"b": 1}}
+
+	expr, err := DynamoUpdate(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, "SET #n0 = :v0, #n1 = :v1, #n2 = :v2", expr.Expression)
+	assert.Equal(t, map[string]string{"#n0": "work", "#n1": "labels", "#n2": "extra"}, expr.Names)
+	assert.Equal(t, map[string]any{
+		":v0": dynamoAddress{City: "Bergen"},
+		":v1": new.Labels,
+		":v2": new.Extra,
+	}, expr.Values)
+}
+
+func TestDynamoUpdate_OnlyRemovals(t *testing.T) {
+	old := newDynamoItem()
+	new := newDynamoItem()
+	new.Nickname = nil
+	new.Work = nil
+
+	expr, err := DynamoUpdate(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, "REMOVE #n0, #n1", expr.Expression)
+	assert.Equal(t, map[string]string{"#n0": "nick", "#n1": "work"}, expr.Names)
+	assert.Nil(t, expr.Values)
+}
+
+func TestDynamoUpdate_NilAndUnreachableValues(t *testing.T) {
+	type Doc struct {
+		Counts map[string]*int   `json:"counts"`
+		Grid   map[[2]int]string `json:"grid"`
+	}
+
+	expr, err := DynamoUpdate(Doc{Counts: map[string]*int{"a": intPtr(1)}}, Doc{Counts: map[string]*int{"a": nil}})
+	require.NoError(t, err)
+	assert.Equal(t, "REMOVE #n0.#n1", expr.Expression)
+	assert.Equal(t, map[string]string{"#n0": "counts", "#n1": "a"}, expr.Names)
+
+	// A changed value that cannot be looked up is an error, not a removal
+	_, err = DynamoUpdate(Doc{Grid: map[[2]int]string{{1, 2}: "a"}}, Doc{Grid: map[[2]int]string{{1, 2}: "b"}})
+	assert.ErrorContains(t, err, "cannot convert map key")
+}
+
+func TestDynamoUpdate_OverlappingPaths(t *testing.T) {
+	type Doc struct {
+		Labels map[string]string `json:"labels"`
+	}
+
+	// The empty key cannot be a path element, so the map is set whole, and
+	// the change below it is left out
+	old := Doc{Labels: map[string]string{"": "a", "b": "x"}}
+	new := Doc{Labels: map[string]string{"": "b", "b": "y"}}
+
+	expr, err := DynamoUpdate(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, "SET #n0 = :v0", expr.Expression)
+	assert.Equal(t, map[string]string{"#n0": "labels"}, expr.Names)
+	assert.Equal(t, map[string]any{":v0": new.Labels}, expr.Values)
+
+	delete(new.Labels, "b")
+	expr, err = DynamoUpdate(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, "SET #n0 = :v0", expr.Expression)
+	assert.Equal(t, map[string]string{"#n0": "labels"}, expr.Names)
+}
+
+func TestDynamoUpdate_NameTag(t *testing.T) {
+	type Doc struct {
+		Name string `json:"name" dynamodbav:"n" ddb:"display_name"`
+	}
+
+	expr, err := DynamoUpdate(Doc{Name: "a"}, Doc{Name: "b"}, NameTag("ddb"))
+	require.NoError(t, err)
+	assert.Equal(t, "SET #n0 = :v0", expr.Expression)
+	assert.Equal(t, map[string]string{"#n0": "display_name"}, expr.Names)
+}
+
+func TestDynamoUpdate_Slices(t *testing.T) {
+	t.Run("append", func(t *testing.T) {
+		old, new := newDynamoItem(), newDynamoItem()
+		new.Tags = []string{"a", "b", "c", "d", "e"}
+
+		expr, err := DynamoUpdate(old, new, ArrayUpdates())
+		require.NoError(t, err)
+		assert.Equal(t, "SET #n0 = list_append(#n0, :v0)", expr.Expression)
+		assert.Equal(t, map[string]string{"#n0": "tags"}, expr.Names)
+		assert.Equal(t, map[string]any{":v0": []any{"d", "e"}}, expr.Values)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		old, new := newDynamoItem(), newDynamoItem()
+		new.Tags = []string{"b"}
+
+		expr, err := DynamoUpdate(old, new, ArrayUpdates())
+		require.NoError(t, err)
+		assert.Equal(t, "REMOVE #n0[0], #n0[2]", expr.Expression)
+		assert.Equal(t, map[string]string{"#n0": "tags"}, expr.Names)
+		assert.Nil(t, expr.Values)
+	})
+
+	t.Run("reorder", func(t *testing.T) {
+		old, new := newDynamoItem(), newDynamoItem()
+		new.Tags = []string{"c", "b", "a"}
+
+		expr, err := DynamoUpdate(old, new, ArrayUpdates())
+		require.NoError(t, err)
+		assert.Equal(t, "SET #n0 = :v0", expr.Expression)
+		assert.Equal(t, map[string]string{"#n0": "tags"}, expr.Names)
+		assert.Equal(t, map[string]any{":v0": []string{"c", "b", "a"}}, expr.Values)
+	})
+
+	t.Run("replaced without ArrayUpdates", func(t *testing.T) {
+		old, new := newDynamoItem(), newDynamoItem()
+		new.Tags = []string{"a", "b", "c", "d"}
+
+		expr, err := DynamoUpdate(old, new)
+		require.NoError(t, err)
+		assert.Equal(t, "SET #n0 = :v0", expr.Expression)
+		assert.Equal(t, map[string]any{":v0": []string{"a", "b", "c", "d"}}, expr.Values)
+	})
+}
+
+func TestDynamoUpdate_ListIndices(t *testing.T) {
+	type Doc struct {
+		Scores [3]int `json:"scores"`
+	}
+
+	expr, err := DynamoUpdate(Doc{Scores: [3]int{1, 2, 3}}, Doc{Scores: [3]int{1, 5, 3}}, DiffArraysByIndex())
+	require.NoError(t, err)
+	assert.Equal(t, "SET #n0[1] = :v0", expr.Expression)
+	assert.Equal(t, map[string]string{"#n0": "scores"}, expr.Names)
+	assert.Equal(t, map[string]any{":v0": 5}, expr.Values)
+}
+
+func TestDynamoUpdate_Maps(t *testing.T) {
+	old := map[string]any{"a": map[string]any{"b": 1, "c": 2}, "gone": true}
+	new := map[string]any{"a": map[string]any{"b": 5, "c": 2}}
+
+	expr, err := DynamoUpdate(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, "SET #n0.#n1 = :v0 REMOVE #n2", expr.Expression)
+	assert.Equal(t, map[string]string{"#n0": "a", "#n1": "b", "#n2": "gone"}, expr.Names)
+	assert.Equal(t, map[string]any{":v0": 5}, expr.Values)
+
+	_, err = DynamoUpdate(map[string]any{"": 1}, map[string]any{"": 2})
+	assert.ErrorContains(t, err, "cannot express change")
+}
@@ -0,0 +1,87 @@
+package structdiff
+
+import (
+	"reflect"
+	"sync"
+)
+
+// Equal reports whether a and b are equal under the same rules as Diff, that
+// is, whether Diff(a, b) would report no differences: structs are compared by
+// their JSON field names, fields tagged `json:"-"` and unexported fields are
+// ignored, a nil pointer field is the same as an absent key, and time.Time
+// values are compared with time.Time.Equal. Pointers to structs are compared
+// by the structs they point to, as in DiffStructs.
+//
+// Equal stops at the first difference and does not build a patch. Comparing
+// structs of the same type or maps does not allocate, unless a struct has to
+// be compared with a map. Values that cannot be diffed, such as values with pointer
+// cycles under the default CycleError, are reported as not equal.
+func Equal(a, b any, opts ...Option) bool {
+	e := equalPool.Get().(*equalState)
+	defer e.release()
+
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&e.o)
+		}
+	}
+
+	d := &e.d
+	var err error
+	if isStructPointer(a) || isStructPointer(b) {
+		err = d.diffStructValues(reflect.ValueOf(a), reflect.ValueOf(b))
+	} else {
+		err = d.diffAny(a, b)
+	}
+	if err != nil {
+		return false
+	}
+	return !e.s.differs
+}
+
+// equalState holds everything a call to Equal needs. It is pooled, since the
+// differ and its sink would otherwise escape to the heap on every call.
+type equalState struct {
+	o options
+	s equalSink
+	d differ
+}
+
+var equalPool = sync.Pool{
+	New: func() any {
+		e := &equalState{}
+		e.d = differ{o: &e.o, sink: &e.s, noValues: true}
+		return e
+	},
+}
+
+// release resets e and returns it to the pool. The path buffer is kept.
+func (e *equalState) release() {
+	e.o = options{}
+	e.s = equalSink{}
+	e.d = differ{o: &e.o, sink: &e.s, path: e.d.path[:0], noValues: true}
+	equalPool.Put(e)
+}
+
+// isStructPointer reports whether v is a pointer to a struct.
+func isStructPointer(v any) bool {
+	t := reflect.TypeOf(v)
+	return t != nil && t.Kind() == reflect.Pointer && t.Elem().Kind() == reflect.Struct
+}
+
+// equalSink records whether any change is reported, and stops the traversal
+// at the first one.
+type equalSink struct {
+	differs bool
+}
+
+func (s *equalSink) enter(path []string) WalkAction {
+	return Continue
+}
+
+func (s *equalSink) change(path []string, kind ChangeKind, old, new any, typ reflect.Type) WalkAction {
+	s.differs = true
+	return Stop
+}
+
+func (s *equalSink) leave(path []string) {}
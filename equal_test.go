@@ -0,0 +1,112 @@
+package structdiff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEqual(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	base := NestedTestStruct{
+		User: TestStruct{
+			Name:    "John",
+			Age:     30,
+			Tags:    []string{"a", "b"},
+			Meta:    map[string]any{"k": 1},
+			Created: created,
+		},
+	}
+
+	t.Run("identical structs", func(t *testing.T) {
+		other := base
+		assert.True(t, Equal(base, other))
+		assert.True(t, Equal(&base, &other))
+	})
+
+	t.Run("changed field", func(t *testing.T) {
+		other := base
+		other.Address.City = "Boston"
+		assert.False(t, Equal(base, other))
+	})
+
+	t.Run("changed nested map", func(t *testing.T) {
+		other := base
+		other.User.Meta = map[string]any{"k": 2}
+		assert.False(t, Equal(base, other))
+	})
+
+	t.Run("times compared with Equal", func(t *testing.T) {
+		other := base
+		other.User.Created = created.In(time.FixedZone("EST", -5*3600))
+		assert.True(t, Equal(base, other))
+
+		other.User.Created = created.Add(time.Second)
+		assert.False(t, Equal(base, other))
+	})
+
+	t.Run("ignored fields", func(t *testing.T) {
+		type Tagged struct {
+			Name    string `json:"name"`
+			Skipped string `json:"-"`
+			private string
+		}
+		assert.True(t, Equal(Tagged{Name: "a", Skipped: "x", private: "y"}, Tagged{Name: "a", Skipped: "z", private: "w"}))
+	})
+
+	t.Run("nil pointer and absent key", func(t *testing.T) {
+		type Opt struct {
+			Name *string `json:"name"`
+		}
+		assert.True(t, Equal(Opt{}, map[string]any{}))
+		assert.False(t, Equal(Opt{Name: stringPtr("a")}, map[string]any{}))
+	})
+
+	t.Run("maps and leaves", func(t *testing.T) {
+		assert.True(t, Equal(map[string]any{"a": []any{1}}, map[string]any{"a": []any{1}}))
+		assert.False(t, Equal(map[string]any{"a": 1}, map[string]any{"a": 1, "b": nil}))
+		assert.True(t, Equal(nil, nil))
+		assert.True(t, Equal(1, 1))
+		assert.False(t, Equal(1, 2))
+	})
+
+	t.Run("cycles", func(t *testing.T) {
+		assert.True(t, Equal(newCycleTree("b"), newCycleTree("b")))
+		assert.False(t, Equal(newCycleTree("b"), newCycleTree("c")))
+		assert.False(t, Equal(newCycleTree("b"), newCycleTree("c"), OnCycle(CycleSkip)))
+	})
+
+	t.Run("matches Diff", func(t *testing.T) {
+		pairs := [][2]any{
+			{base, base},
+			{User{Name: "a"}, User{Name: "b"}},
+			{User{Name: "a"}, map[string]any{"name": "a", "age": 0, "email": ""}},
+			{map[string]any{"x": User{Age: 1}}, map[string]any{"x": User{Age: 1}}},
+			{map[string]any{"x": User{Age: 1}}, map[string]any{"x": User{Age: 2}}},
+		}
+		for _, p := range pairs {
+			diff, err := Diff(p[0], p[1])
+			assert.NoError(t, err)
+			noChanges := diff == nil || len(diff.(map[string]any)) == 0
+			assert.Equal(t, noChanges, Equal(p[0], p[1]), "%v vs %v", p[0], p[1])
+		}
+	})
+}
+
+func TestEqual_Allocs(t *testing.T) {
+	created := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	old := NestedStruct{
+		User:    SimpleStruct{Name: "John", Age: 30, Email: "john@example.com"},
+		Address: AddressStruct{Street: "123 Main St", City: "NYC"},
+		Tags:    []string{"a", "b"},
+		Meta:    map[string]any{"score": 95.5},
+		Created: created,
+	}
+	same := old
+	changed := old
+	changed.Address.City = "Boston"
+
+	assert.Zero(t, testing.AllocsPerRun(100, func() { Equal(&old, &same) }))
+	assert.Zero(t, testing.AllocsPerRun(100, func() { Equal(&old, &changed) }))
+}
@@ -127,3 +127,17 @@ func ExampleDiffStructs_roundTrip() {
 	// Result: Bob, born 1985-05-15
 	// Matches target: true
 }
+
+func ExampleFormat() {
+	type User struct {
+		Name string `json:"name"`
+		Age  int    `json:"age"`
+	}
+
+	changes, _ := structdiff.Changes(User{Name: "John", Age: 30}, User{Name: "Johnny", Age: 30})
+	out, _ := structdiff.Format(changes, structdiff.FormatUnified)
+	fmt.Print(out)
+	// Output:
+	// - name: "John"
+	// + name: "Johnny"
+}
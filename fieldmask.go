@@ -0,0 +1,335 @@
+package structdiff
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// FieldMaskPaths returns the dotted paths of the values a patch changes, as
+// produced by DiffStructs, sorted, for use as the paths of a protobuf
+// FieldMask:
+//
+//	{"name": "Ann", "homeAddress": {"city": "Oslo"}}
+//
+// gives ["homeAddress.city", "name"], or ["home_address.city", "name"] with
+// SnakeCaseNames. Paths are built as by Flatten, so a value within a map
+// field is addressed by its key, and dots within keys are escaped. Returns
+// nil for an empty patch.
+func FieldMaskPaths(patch map[string]any, opts ...Option) []string {
+	if len(patch) == 0 {
+		return nil
+	}
+	o := newOptions(opts)
+
+	seen := make(map[string]bool)
+	paths := make([]string, 0, len(patch))
+	for path := range Flatten(patch) {
+		if o.snakeCaseType != nil {
+			keys, _ := splitEscapedPath(path)
+			path = joinEscapedPath(snakeCaseFieldNames(o.snakeCaseType, keys, o))
+		}
+		if !seen[path] {
+			seen[path] = true
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// ApplyWithMask copies the values at the given dotted paths from source to
+// the struct target points to, leaving the rest of target unchanged, as a
+// protobuf field mask update does. Path elements name struct fields by their
+// JSON name or its snake_case form, map entries by their key and array
+// elements by their index, so the output of FieldMaskPaths, with or without
+// SnakeCaseNames, is accepted.
+//
+// Each listed value replaces the one in target whole, converted as by Set:
+// a struct field or map listed by itself is not merged with its previous
+// value. Nil pointers leading to a listed value are allocated. A value that
+// is nil or missing in source, such as a field below a nil pointer, clears
+// the one in target: map entries are deleted and other values set to their
+// zero value. Paths below a listed path are covered by it.
+//
+// source is usually of the same type as target, but may be any value Get
+// accepts, such as a map returned by ToMap. Values are deep copies of those
+// in source, fields tagged `diff:"redact"` included. Returns an error if a
+// path is invalid for the type of target, or if a value cannot be converted.
+func ApplyWithMask(target, source any, paths []string, opts ...Option) error {
+	v, err := pointerTarget(target)
+	if err != nil {
+		return err
+	}
+	o := newOptions(opts)
+
+	type maskEntry struct {
+		path    []string
+		leaf    reflect.Type
+		inMap   bool
+		pointer string
+	}
+	entries := make([]maskEntry, 0, len(paths))
+	for _, path := range paths {
+		keys, err := splitEscapedPath(path)
+		if err != nil {
+			return err
+		}
+		names, leaf, inMap, err := resolveMaskPath(v.Type(), keys, o)
+		if err != nil {
+			return fmt.Errorf("field mask path %q: %w", path, err)
+		}
+		entries = append(entries, maskEntry{names, leaf, inMap, formatPointer(names)})
+	}
+
+	// Apply shorter paths first, skipping those they cover
+	sort.SliceStable(entries, func(i, j int) bool {
+		return len(entries[i].path) < len(entries[j].path)
+	})
+	var applied [][]string
+	for _, entry := range entries {
+		if maskCovered(applied, entry.path) {
+			continue
+		}
+		applied = append(applied, entry.path)
+
+		var value any
+		if found, err := Get(source, entry.pointer); err == nil {
+			// Pointers are followed as far as target has them, since Set
+			// allocates its own
+			fv := reflect.ValueOf(found)
+			for leaf := entry.leaf; leaf != nil && leaf.Kind() == reflect.Pointer && fv.Kind() == reflect.Pointer && !fv.IsNil(); leaf = leaf.Elem() {
+				fv = fv.Elem()
+			}
+			if !isNilValue(fv) {
+				value = cloneValue(fv, make(map[clonedPointer]reflect.Value)).Interface()
+			}
+		}
+
+		if value == nil {
+			if err := clearMaskedValue(target, entry.pointer, entry.leaf, entry.inMap, opts); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := Set(target, entry.pointer, value, opts...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolveMaskPath translates a field mask path within a value of type t into
+// the JSON names of its fields, and returns the type of the value it refers
+// to, or nil if unknown, and whether that value is a map entry.
+func resolveMaskPath(t reflect.Type, keys []string, o *options) (names []string, leaf reflect.Type, inMap bool, err error) {
+	names = make([]string, len(keys))
+	for i, key := range keys {
+		names[i] = key
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil {
+			continue
+		}
+
+		inMap = false
+		switch t.Kind() {
+		case reflect.Struct:
+			index, field, err := findFieldByJSONName(t, key, o)
+			if err != nil {
+				if index = findFieldBySnakeName(t, key); index < 0 {
+					return nil, nil, false, err
+				}
+				field = t.Field(index)
+			}
+			names[i] = parseName(field.Tag.Get("json"), field.Name)
+			t = field.Type
+		case reflect.Map:
+			inMap = true
+			t = t.Elem()
+		case reflect.Slice, reflect.Array:
+			n := math.MaxInt
+			if t.Kind() == reflect.Array {
+				n = t.Len()
+			}
+			if _, err := parseIndex(key, n); err != nil {
+				return nil, nil, false, err
+			}
+			t = t.Elem()
+		case reflect.Interface:
+			t = nil
+		default:
+			return nil, nil, false, fmt.Errorf("cannot select %q within %s", key, t)
+		}
+	}
+	return names, t, inMap, nil
+}
+
+// clonedPointer identifies a pointer copied by cloneValue.
+type clonedPointer struct {
+	addr uintptr
+	typ  reflect.Type
+}
+
+// cloneValue returns a deep copy of v, for ApplyWithMask not to share slices,
+// maps or pointees with source. Unlike ToMap it keeps the types of values and
+// does not redact them. Pointers found more than once, as in cycles, are
+// copied once; unexported struct fields are copied shallowly.
+func cloneValue(v reflect.Value, seen map[clonedPointer]reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		key := clonedPointer{v.Pointer(), v.Type()}
+		if c, ok := seen[key]; ok {
+			return c
+		}
+		c := reflect.New(v.Type().Elem())
+		seen[key] = c
+		c.Elem().Set(cloneValue(v.Elem(), seen))
+		return c
+
+	case reflect.Interface:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type()).Elem()
+		c.Set(cloneValue(v.Elem(), seen))
+		return c
+
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i), seen))
+		}
+		return c
+
+	case reflect.Array:
+		c := reflect.New(v.Type()).Elem()
+		for i := 0; i < v.Len(); i++ {
+			c.Index(i).Set(cloneValue(v.Index(i), seen))
+		}
+		return c
+
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			c.SetMapIndex(iter.Key(), cloneValue(iter.Value(), seen))
+		}
+		return c
+
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := 0; i < v.NumField(); i++ {
+			if v.Type().Field(i).IsExported() {
+				c.Field(i).Set(cloneValue(v.Field(i), seen))
+			}
+		}
+		return c
+	}
+	return v
+}
+
+// snakeCaseFieldNames converts the elements of a path within a value of type
+// t that name struct fields to snake_case, keeping map keys and indices, and
+// the elements below values of unknown type, as they are.
+func snakeCaseFieldNames(t reflect.Type, keys []string, o *options) []string {
+	for i, key := range keys {
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil {
+			break
+		}
+
+		switch t.Kind() {
+		case reflect.Struct:
+			_, field, err := findFieldByJSONName(t, key, o)
+			if err != nil {
+				return keys
+			}
+			keys[i] = snakeCase(parseName(field.Tag.Get("json"), field.Name))
+			t = field.Type
+		case reflect.Map, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		default:
+			t = nil
+		}
+	}
+	return keys
+}
+
+// findFieldBySnakeName returns the index of the field of structType whose JSON
+// name in snake_case is name, or -1.
+func findFieldBySnakeName(structType reflect.Type, name string) int {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		tag := field.Tag.Get("json")
+		if field.IsExported() && tag != "-" && snakeCase(parseName(tag, field.Name)) == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// maskCovered reports whether path equals or lies below one of paths.
+func maskCovered(paths [][]string, path []string) bool {
+	for _, p := range paths {
+		if len(p) <= len(path) && reflect.DeepEqual(p, path[:len(p)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// clearMaskedValue clears the value at ptr within target, of type leaf, for
+// ApplyWithMask: a map entry is deleted, and other values are set to their
+// zero value. Values that do not exist in target are left alone.
+func clearMaskedValue(target any, ptr string, leaf reflect.Type, inMap bool, opts []Option) error {
+	if _, err := Get(target, ptr, opts...); err != nil {
+		return nil
+	}
+	if inMap {
+		return Delete(target, ptr, opts...)
+	}
+	var zero any
+	if leaf != nil {
+		if z := reflect.Zero(leaf); !isNilValue(z) {
+			zero = z.Interface()
+		}
+	}
+	return Set(target, ptr, zero, opts...)
+}
+
+// snakeCase converts a camelCase or PascalCase name to snake_case, keeping
+// acronyms together: "userID" becomes "user_id" and "HTTPServer"
+// "http_server".
+func snakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			acronymEnd := i > 0 && unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || acronymEnd {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,242 @@
+package structdiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type maskAddress struct {
+	Street  string `json:"street"`
+	ZipCode string `json:"zipCode"`
+}
+
+type maskUser struct {
+	UserID      string            `json:"userID"`
+	DisplayName string            `json:"displayName"`
+	Nickname    *string           `json:"nickname"`
+	HomeAddress maskAddress       `json:"homeAddress"`
+	Work        *maskAddress      `json:"work"`
+	Tags        []string          `json:"tags"`
+	Labels      map[string]string `json:"labels"`
+	Scores      [3]int            `json:"scores"`
+}
+
+func newMaskUser() maskUser {
+	nick := "annie"
+	return maskUser{
+		UserID:      "u1",
+		DisplayName: "Ann",
+		Nickname:    &nick,
+		HomeAddress: maskAddress{Street: "Main", ZipCode: "0150"},
+		Work:        &maskAddress{Street: "Dock", ZipCode: "0250"},
+		Tags:        []string{"a", "b"},
+		Labels:      map[string]string{"team": "core", "tier": "1"},
+	}
+}
+
+func TestFieldMaskPaths(t *testing.T) {
+	old := newMaskUser()
+	new := newMaskUser()
+	new.DisplayName = "Anna"
+	new.Nickname = nil
+	new.HomeAddress.ZipCode = "5003"
+	new.Tags = []string{"a"}
+	new.Labels = map[string]string{"team": "infra", "tier": "1"}
+
+	patch, err := DiffStructs(old, new)
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"displayName", "homeAddress.zipCode", "labels.team", "nickname", "tags"}, FieldMaskPaths(patch))
+	assert.Equal(t, []string{"display_name", "home_address.zip_code", "labels.team", "nickname", "tags"}, FieldMaskPaths(patch, SnakeCaseNames(old)))
+}
+
+func TestFieldMaskPaths_SnakeCaseKeepsMapKeys(t *testing.T) {
+	old := newMaskUser()
+	new := newMaskUser()
+	new.Labels["appVersion"] = "2"
+	new.Work.ZipCode = "9000"
+
+	patch, err := DiffStructs(old, new)
+	require.NoError(t, err)
+	paths := FieldMaskPaths(patch, SnakeCaseNames(old))
+	assert.Equal(t, []string{"labels.appVersion", "work.zip_code"}, paths)
+
+	target := newMaskUser()
+	require.NoError(t, ApplyWithMask(&target, new, paths))
+	assert.Equal(t, "2", target.Labels["appVersion"])
+}
+
+func TestFieldMaskPaths_Empty(t *testing.T) {
+	assert.Nil(t, FieldMaskPaths(nil))
+	assert.Nil(t, FieldMaskPaths(map[string]any{}))
+}
+
+func TestFieldMaskPaths_EscapesKeys(t *testing.T) {
+	patch := map[string]any{"labels": map[string]any{"app.io/name": "web"}}
+	assert.Equal(t, []string{`labels.app\.io/name`}, FieldMaskPaths(patch))
+}
+
+func TestSnakeCase(t *testing.T) {
+	tests := map[string]string{
+		"name":        "name",
+		"displayName": "display_name",
+		"userID":      "user_id",
+		"HTTPServer":  "http_server",
+		"ipV4Address": "ip_v4_address",
+		"already_set": "already_set",
+		"":            "",
+	}
+	for in, want := range tests {
+		assert.Equal(t, want, snakeCase(in), in)
+	}
+}
+
+func TestApplyWithMask(t *testing.T) {
+	target := newMaskUser()
+	source := newMaskUser()
+	source.DisplayName = "Anna"
+	source.HomeAddress = maskAddress{Street: "Elm", ZipCode: "5003"}
+	source.Tags = []string{"x"}
+	source.UserID = "ignored"
+
+	err := ApplyWithMask(&target, source, []string{"displayName", "home_address.zip_code", "tags"})
+	require.NoError(t, err)
+
+	want := newMaskUser()
+	want.DisplayName = "Anna"
+	want.HomeAddress.ZipCode = "5003"
+	want.Tags = []string{"x"}
+	assert.Equal(t, want, target)
+
+	// The copied slice does not share its backing array with source
+	source.Tags[0] = "y"
+	assert.Equal(t, []string{"x"}, target.Tags)
+}
+
+func TestApplyWithMask_ReplacesWhole(t *testing.T) {
+	target := newMaskUser()
+	source := maskUser{
+		HomeAddress: maskAddress{Street: "Elm"},
+		Labels:      map[string]string{"team": "infra"},
+	}
+
+	err := ApplyWithMask(&target, source, []string{"homeAddress", "labels"})
+	require.NoError(t, err)
+	assert.Equal(t, maskAddress{Street: "Elm"}, target.HomeAddress)
+	assert.Equal(t, map[string]string{"team": "infra"}, target.Labels)
+}
+
+func TestApplyWithMask_Clears(t *testing.T) {
+	target := newMaskUser()
+	source := newMaskUser()
+	source.Nickname = nil
+	source.Work = nil
+	source.Labels = map[string]string{"team": "core"}
+
+	err := ApplyWithMask(&target, source, []string{"nickname", "work.zipCode", "labels.tier", "labels.missing"})
+	require.NoError(t, err)
+	assert.Nil(t, target.Nickname)
+	assert.Equal(t, &maskAddress{Street: "Dock"}, target.Work)
+	assert.Equal(t, map[string]string{"team": "core"}, target.Labels)
+}
+
+func TestApplyWithMask_AllocatesPointers(t *testing.T) {
+	var target maskUser
+	source := newMaskUser()
+
+	err := ApplyWithMask(&target, &source, []string{"work.street", "scores.1"})
+	require.NoError(t, err)
+	assert.Equal(t, &maskAddress{Street: "Dock"}, target.Work)
+
+	source.Scores[1] = 7
+	require.NoError(t, ApplyWithMask(&target, &source, []string{"scores.1"}))
+	assert.Equal(t, [3]int{0, 7, 0}, target.Scores)
+}
+
+func TestApplyWithMask_CoveredPaths(t *testing.T) {
+	target := newMaskUser()
+	source := maskUser{HomeAddress: maskAddress{Street: "Elm", ZipCode: "5003"}}
+
+	err := ApplyWithMask(&target, source, []string{"homeAddress.street", "homeAddress"})
+	require.NoError(t, err)
+	assert.Equal(t, source.HomeAddress, target.HomeAddress)
+}
+
+func TestApplyWithMask_RedactedFields(t *testing.T) {
+	type Owner struct {
+		Name     string `json:"name"`
+		Password string `json:"password" diff:"redact"`
+	}
+	type Account struct {
+		Owner   Owner            `json:"owner"`
+		Backup  *Owner           `json:"backup"`
+		Members map[string]Owner `json:"members"`
+	}
+
+	var target Account
+	source := Account{
+		Owner:   Owner{Name: "alice", Password: "s3cret"},
+		Backup:  &Owner{Name: "bob", Password: "hunter2"},
+		Members: map[string]Owner{"carol": {Password: "tr0ub4dor"}},
+	}
+
+	err := ApplyWithMask(&target, source, []string{"owner", "backup", "members"})
+	require.NoError(t, err)
+	assert.Equal(t, source, target)
+
+	// The copied pointee is not shared with source
+	source.Backup.Password = "changed"
+	assert.Equal(t, "hunter2", target.Backup.Password)
+}
+
+func TestApplyWithMask_RoundTrip(t *testing.T) {
+	old := newMaskUser()
+	new := newMaskUser()
+	new.DisplayName = "Anna"
+	new.Work.ZipCode = "9000"
+	new.Labels = map[string]string{"team": "core"}
+	new.Scores = [3]int{1, 2, 3}
+
+	patch, err := DiffStructs(old, new)
+	require.NoError(t, err)
+
+	for _, opts := range [][]Option{nil, {SnakeCaseNames(&old)}} {
+		target := newMaskUser()
+		require.NoError(t, ApplyWithMask(&target, new, FieldMaskPaths(patch, opts...)))
+		assert.Equal(t, new, target)
+	}
+}
+
+func TestApplyWithMask_Errors(t *testing.T) {
+	t.Run("not a pointer", func(t *testing.T) {
+		err := ApplyWithMask(maskUser{}, newMaskUser(), []string{"tags"})
+		assert.ErrorContains(t, err, "non-nil pointer")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		err := ApplyWithMask(&maskUser{}, newMaskUser(), []string{"email"})
+		assert.ErrorContains(t, err, `field "email" not found`)
+	})
+
+	t.Run("below a scalar", func(t *testing.T) {
+		err := ApplyWithMask(&maskUser{}, newMaskUser(), []string{"displayName.first"})
+		assert.ErrorContains(t, err, `cannot select "first"`)
+	})
+
+	t.Run("bad escape", func(t *testing.T) {
+		err := ApplyWithMask(&maskUser{}, newMaskUser(), []string{`labels.a\b`})
+		assert.ErrorContains(t, err, "invalid escape")
+	})
+
+	t.Run("bad index", func(t *testing.T) {
+		err := ApplyWithMask(&maskUser{}, newMaskUser(), []string{"scores.x"})
+		assert.ErrorContains(t, err, "invalid index")
+	})
+
+	t.Run("index out of range", func(t *testing.T) {
+		err := ApplyWithMask(&maskUser{}, newMaskUser(), []string{"scores.3"})
+		assert.ErrorContains(t, err, "out of range")
+	})
+}
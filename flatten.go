@@ -0,0 +1,130 @@
+package structdiff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Flatten converts a patch into a flat patch, in which the changes within
+// nested maps are keyed by their dotted path:
+//
+//	{"address": {"city": "Boston"}, "tags": nil}
+//
+// becomes
+//
+//	{"address.city": "Boston", "tags": nil}
+//
+// Dots and backslashes within keys are escaped with a backslash, so a key
+// "a.b" becomes "a\.b". Empty nested maps are kept as values, so that
+// Unflatten restores them. The patch is not modified.
+func Flatten(patch map[string]any) map[string]any {
+	if patch == nil {
+		return nil
+	}
+	flat := make(map[string]any, len(patch))
+	flattenInto(flat, "", patch)
+	return flat
+}
+
+// Unflatten converts a flat patch as produced by Flatten back into a nested
+// patch. Returns an error if a key is not a valid escaped path, or if a key
+// is a prefix of another, as in {"a": 1, "a.b": 2}.
+func Unflatten(flat map[string]any) (map[string]any, error) {
+	if flat == nil {
+		return nil, nil
+	}
+
+	patch := make(map[string]any)
+	for key, value := range flat {
+		path, err := splitEscapedPath(key)
+		if err != nil {
+			return nil, err
+		}
+
+		m := patch
+		for i, name := range path[:len(path)-1] {
+			if _, isLeaf := flat[joinEscapedPath(path[:i+1])]; isLeaf {
+				return nil, fmt.Errorf("conflicting keys %q and %q", joinEscapedPath(path[:i+1]), key)
+			}
+			child, ok := m[name].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				m[name] = child
+			}
+			m = child
+		}
+
+		m[path[len(path)-1]] = value
+	}
+	return patch, nil
+}
+
+// ApplyFlatToStruct applies a flat patch, as produced by Flatten, to a
+// struct. Each dotted path is resolved field by field using JSON names, as
+// ApplyToStruct does for nested patches; path elements below a map field are
+// its keys, and below an array field its indices. Slice elements cannot be
+// addressed.
+//
+// Options are interpreted as by ApplyToStruct.
+func ApplyFlatToStruct(target any, flat map[string]any, opts ...Option) error {
+	patch, err := Unflatten(flat)
+	if err != nil {
+		return err
+	}
+	return ApplyToStruct(target, patch, opts...)
+}
+
+// keyEscaper escapes the separator and the escape character in keys joined
+// into dotted paths.
+var keyEscaper = strings.NewReplacer(`\`, `\\`, `.`, `\.`)
+
+// escapeKey escapes a single key for use in a dotted path.
+func escapeKey(key string) string {
+	return keyEscaper.Replace(key)
+}
+
+// joinEscapedPath joins keys into a dotted path, escaping each.
+func joinEscapedPath(path []string) string {
+	escaped := make([]string, len(path))
+	for i, key := range path {
+		escaped[i] = escapeKey(key)
+	}
+	return strings.Join(escaped, ".")
+}
+
+// splitEscapedPath splits a dotted path into its unescaped keys.
+func splitEscapedPath(path string) ([]string, error) {
+	var keys []string
+	var key strings.Builder
+	for i := 0; i < len(path); i++ {
+		switch c := path[i]; c {
+		case '\\':
+			if i+1 == len(path) || (path[i+1] != '\\' && path[i+1] != '.') {
+				return nil, fmt.Errorf("invalid escape in path %q", path)
+			}
+			i++
+			key.WriteByte(path[i])
+		case '.':
+			keys = append(keys, key.String())
+			key.Reset()
+		default:
+			key.WriteByte(c)
+		}
+	}
+	return append(keys, key.String()), nil
+}
+
+// flattenInto stores the leaves of m in flat, keyed by their dotted paths
+// after prefix, which is empty or ends with a dot. Nested maps are flattened
+// unless empty, in which case they are kept as leaves so that they are not
+// lost.
+func flattenInto(flat map[string]any, prefix string, m map[string]any) {
+	for key, value := range m {
+		path := prefix + escapeKey(key)
+		if nested, ok := value.(map[string]any); ok && len(nested) > 0 {
+			flattenInto(flat, path+".", nested)
+			continue
+		}
+		flat[path] = value
+	}
+}
@@ -0,0 +1,152 @@
+package structdiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlatten(t *testing.T) {
+	patch := map[string]any{
+		"address": map[string]any{"city": "Boston", "geo": map[string]any{"lat": 42.36}},
+		"tags":    nil,
+		"labels":  map[string]any{"app.kubernetes.io/name": "web", `a\b`: "x"},
+		"empty":   map[string]any{},
+		"list":    []any{map[string]any{"a": 1}},
+	}
+
+	flat := Flatten(patch)
+	assert.Equal(t, map[string]any{
+		"address.city":                    "Boston",
+		"address.geo.lat":                 42.36,
+		"tags":                            nil,
+		`labels.app\.kubernetes\.io/name`: "web",
+		`labels.a\\b`:                     "x",
+		"empty":                           map[string]any{},
+		"list":                            []any{map[string]any{"a": 1}},
+	}, flat)
+
+	restored, err := Unflatten(flat)
+	require.NoError(t, err)
+	assert.Equal(t, patch, restored)
+
+	assert.Nil(t, Flatten(nil))
+}
+
+func TestUnflatten_Errors(t *testing.T) {
+	tests := []struct {
+		name   string
+		flat   map[string]any
+		errMsg string
+	}{
+		{"prefix conflict", map[string]any{"a": 1, "a.b": 2}, "conflicting keys"},
+		{"map prefix conflict", map[string]any{"a": map[string]any{}, "a.b": 2}, "conflicting keys"},
+		{"trailing backslash", map[string]any{`a\`: 1}, "invalid escape"},
+		{"unknown escape", map[string]any{`a\b`: 1}, "invalid escape"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Unflatten(tt.flat)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.errMsg)
+		})
+	}
+}
+
+func TestSplitEscapedPath(t *testing.T) {
+	tests := []struct {
+		path string
+		keys []string
+	}{
+		{"a", []string{"a"}},
+		{"a.b.c", []string{"a", "b", "c"}},
+		{`a\.b.c`, []string{"a.b", "c"}},
+		{`a\\.b`, []string{`a\`, "b"}},
+		{"a..b", []string{"a", "", "b"}},
+		{"", []string{""}},
+	}
+
+	for _, tt := range tests {
+		keys, err := splitEscapedPath(tt.path)
+		require.NoError(t, err)
+		assert.Equal(t, tt.keys, keys, tt.path)
+		assert.Equal(t, tt.path, joinEscapedPath(keys))
+	}
+}
+
+func TestApplyFlatToStruct(t *testing.T) {
+	type Address struct {
+		Street string `json:"street"`
+		City   string `json:"city"`
+	}
+	type Person struct {
+		Name    string            `json:"name"`
+		Address Address           `json:"address"`
+		Work    *Address          `json:"work"`
+		Tags    []string          `json:"tags"`
+		Labels  map[string]string `json:"labels"`
+		Scores  [3]int            `json:"scores"`
+	}
+
+	person := Person{
+		Name:    "Ann",
+		Address: Address{Street: "Main", City: "Oslo"},
+		Tags:    []string{"a"},
+		Labels:  map[string]string{"team": "core"},
+	}
+
+	err := ApplyFlatToStruct(&person, map[string]any{
+		"address.city":        "Boston",
+		"work.city":           "Cambridge",
+		"tags":                nil,
+		`labels.app\.io/name`: "web",
+		"scores.1":            7,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, Person{
+		Name:    "Ann",
+		Address: Address{Street: "Main", City: "Boston"},
+		Work:    &Address{City: "Cambridge"},
+		Labels:  map[string]string{"team": "core", "app.io/name": "web"},
+		Scores:  [3]int{0, 7, 0},
+	}, person)
+}
+
+func TestApplyFlatToStruct_Errors(t *testing.T) {
+	type Target struct {
+		Name string `json:"name"`
+	}
+
+	var target Target
+	err := ApplyFlatToStruct(&target, map[string]any{"missing.field": 1})
+	assert.ErrorContains(t, err, "missing")
+
+	err = ApplyFlatToStruct(&target, map[string]any{"name": "x", "name.first": "y"})
+	assert.ErrorContains(t, err, "conflicting keys")
+
+	assert.NoError(t, ApplyFlatToStruct(&target, map[string]any{"missing.field": 1}, IgnoreUnknownFields()))
+}
+
+func TestFlatten_DiffRoundTrip(t *testing.T) {
+	type Address struct {
+		City string `json:"city"`
+		Zip  string `json:"zip"`
+	}
+	type Person struct {
+		Name    string  `json:"name"`
+		Address Address `json:"address"`
+	}
+
+	old := Person{Name: "Ann", Address: Address{City: "Oslo", Zip: "0150"}}
+	new := Person{Name: "Ann", Address: Address{City: "Boston", Zip: "0150"}}
+
+	patch, err := DiffStructs(old, new)
+	require.NoError(t, err)
+	flat := Flatten(patch)
+	assert.Equal(t, map[string]any{"address.city": "Boston"}, flat)
+
+	require.NoError(t, ApplyFlatToStruct(&old, flat))
+	assert.Equal(t, new, old)
+}
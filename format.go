@@ -0,0 +1,200 @@
+package structdiff
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// FormatStyle selects the layout used by Format.
+type FormatStyle int
+
+const (
+	// FormatUnified renders one line per value, like a unified diff: old
+	// values on lines starting with "-" and new values on lines starting
+	// with "+", each prefixed with its dotted path.
+	FormatUnified FormatStyle = iota
+
+	// FormatTree renders the changed paths as an indented tree, with each
+	// leaf marked "+" (added), "-" (removed) or "~" (modified).
+	FormatTree
+
+	// FormatColor renders like FormatUnified, with ANSI colors for terminals.
+	FormatColor
+)
+
+// ANSI escape sequences used by FormatColor
+const (
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiReset = "\x1b[0m"
+)
+
+// Format renders a diff as human-readable text. diff is either a list of
+// changes, as returned by Changes, or a patch, as returned by Diff; a patch is
+// converted with ChangesFromPatch.
+//
+// Old values are shown when they are known, which is only the case for
+// changes from Changes. Since a patch records only new values, the rendering
+// of a patch shows new values only and marks removals without the removed
+// value.
+//
+// Values are rendered as JSON where possible, and with fmt otherwise. Returns
+// an error if diff is neither a []Change nor a map[string]any.
+func Format(diff any, style FormatStyle) (string, error) {
+	var changes []Change
+	switch d := diff.(type) {
+	case []Change:
+		changes = d
+	case map[string]any:
+		changes = ChangesFromPatch(d)
+	case nil:
+	default:
+		return "", fmt.Errorf("cannot format %T: expected []Change or map[string]any", diff)
+	}
+
+	var sb strings.Builder
+	switch style {
+	case FormatUnified:
+		formatUnified(&sb, changes, false)
+	case FormatTree:
+		formatTree(&sb, changes)
+	case FormatColor:
+		formatUnified(&sb, changes, true)
+	default:
+		return "", fmt.Errorf("unknown format style %d", style)
+	}
+	return sb.String(), nil
+}
+
+// formatUnified writes a "-" line for each known old value and a "+" line
+// for each new value.
+func formatUnified(sb *strings.Builder, changes []Change, color bool) {
+	line := func(marker, path string, value any, hasValue bool, ansi string) {
+		if color {
+			sb.WriteString(ansi)
+		}
+		sb.WriteString(marker)
+		sb.WriteString(" ")
+		sb.WriteString(path)
+		if hasValue {
+			sb.WriteString(": ")
+			sb.WriteString(formatValue(value))
+		}
+		if color {
+			sb.WriteString(ansiReset)
+		}
+		sb.WriteString("\n")
+	}
+
+	for _, c := range changes {
+		path := formatPath(c.Path)
+		if c.Kind != Added && (c.Old != nil || c.Kind == Removed) {
+			line("-", path, c.Old, c.Old != nil, ansiRed)
+		}
+		if c.Kind != Removed {
+			line("+", path, c.New, true, ansiGreen)
+		}
+	}
+}
+
+// formatTree writes each change below the path components it shares with
+// the previous change, indented two spaces per level.
+func formatTree(sb *strings.Builder, changes []Change) {
+	var prev []string
+	for _, c := range changes {
+		if len(c.Path) == 0 {
+			writeTreeLeaf(sb, c, formatPath(nil), 0)
+			prev = nil
+			continue
+		}
+
+		// Open the parents not shared with the previous change
+		parents := c.Path[:len(c.Path)-1]
+		common := 0
+		for common < len(parents) && common < len(prev) && parents[common] == prev[common] {
+			common++
+		}
+		for depth := common; depth < len(parents); depth++ {
+			sb.WriteString("  ")
+			sb.WriteString(strings.Repeat("  ", depth))
+			sb.WriteString(formatKey(parents[depth]))
+			sb.WriteString("\n")
+		}
+
+		writeTreeLeaf(sb, c, formatKey(c.Path[len(c.Path)-1]), len(parents))
+		prev = parents
+	}
+}
+
+func writeTreeLeaf(sb *strings.Builder, c Change, key string, depth int) {
+	switch c.Kind {
+	case Added:
+		sb.WriteString("+ ")
+	case Removed:
+		sb.WriteString("- ")
+	default:
+		sb.WriteString("~ ")
+	}
+	sb.WriteString(strings.Repeat("  ", depth))
+	sb.WriteString(key)
+
+	switch {
+	case c.Kind == Removed:
+		if c.Old != nil {
+			sb.WriteString(": ")
+			sb.WriteString(formatValue(c.Old))
+		}
+	case c.Kind == Modified && c.Old != nil:
+		sb.WriteString(": ")
+		sb.WriteString(formatValue(c.Old))
+		sb.WriteString(" -> ")
+		sb.WriteString(formatValue(c.New))
+	default:
+		sb.WriteString(": ")
+		sb.WriteString(formatValue(c.New))
+	}
+	sb.WriteString("\n")
+}
+
+// formatPath joins path with dots, quoting keys that would be ambiguous. The
+// empty path, for a change to the compared values themselves, is "(root)".
+func formatPath(path []string) string {
+	if len(path) == 0 {
+		return "(root)"
+	}
+	var sb strings.Builder
+	for i, key := range path {
+		formatted := formatKey(key)
+		if i > 0 && !strings.HasPrefix(formatted, "[") {
+			sb.WriteString(".")
+		}
+		sb.WriteString(formatted)
+	}
+	return sb.String()
+}
+
+// formatKey returns key as is, or as a quoted bracketed key, e.g. ["a.b"], if
+// it is empty or contains characters that would make a dotted path ambiguous.
+func formatKey(key string) string {
+	if key == "" || strings.ContainsAny(key, `.[]" `) {
+		return "[" + fmt.Sprintf("%q", key) + "]"
+	}
+	return key
+}
+
+// formatValue renders a value as JSON, or with fmt if it cannot be marshaled.
+// Unlike json.Marshal, it does not escape HTML characters. Redacted
+// placeholders are rendered as is.
+func formatValue(v any) string {
+	if r, ok := v.(Redacted); ok {
+		return string(r)
+	}
+	var sb strings.Builder
+	enc := json.NewEncoder(&sb)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err == nil {
+		return strings.TrimSuffix(sb.String(), "\n")
+	}
+	return fmt.Sprintf("%v", v)
+}
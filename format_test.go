@@ -0,0 +1,169 @@
+package structdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type formatAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type formatPerson struct {
+	Name     string            `json:"name"`
+	Age      int               `json:"age"`
+	Nickname *string           `json:"nickname"`
+	Address  formatAddress     `json:"address"`
+	Labels   map[string]string `json:"labels"`
+}
+
+func formatPair() (formatPerson, formatPerson) {
+	old := formatPerson{
+		Name:     "Alice",
+		Age:      30,
+		Nickname: stringPtr("Al"),
+		Address:  formatAddress{Street: "1 Main St", City: "NYC"},
+		Labels:   map[string]string{"team": "x"},
+	}
+	new := formatPerson{
+		Name:    "Alice",
+		Age:     31,
+		Address: formatAddress{Street: "1 Main St", City: "Boston"},
+		Labels:  map[string]string{"team": "x", "zone": "1"},
+	}
+	return old, new
+}
+
+func TestFormat_Unified(t *testing.T) {
+	old, new := formatPair()
+
+	t.Run("from changes", func(t *testing.T) {
+		changes, err := Changes(old, new)
+		require.NoError(t, err)
+
+		out, err := Format(changes, FormatUnified)
+		require.NoError(t, err)
+
+		expected := strings.Join([]string{
+			"- age: 30",
+			"+ age: 31",
+			`- nickname: "Al"`,
+			`- address.city: "NYC"`,
+			`+ address.city: "Boston"`,
+			`+ labels.zone: "1"`,
+		}, "\n") + "\n"
+		assert.Equal(t, expected, out)
+	})
+
+	t.Run("from patch", func(t *testing.T) {
+		patch, err := Diff(old, new)
+		require.NoError(t, err)
+
+		out, err := Format(patch, FormatUnified)
+		require.NoError(t, err)
+
+		// Without old values, only new values and removals are shown
+		expected := strings.Join([]string{
+			`+ address.city: "Boston"`,
+			"+ age: 31",
+			`+ labels.zone: "1"`,
+			"- nickname",
+		}, "\n") + "\n"
+		assert.Equal(t, expected, out)
+	})
+}
+
+func TestFormat_Tree(t *testing.T) {
+	old, new := formatPair()
+
+	t.Run("from changes", func(t *testing.T) {
+		changes, err := Changes(old, new)
+		require.NoError(t, err)
+
+		out, err := Format(changes, FormatTree)
+		require.NoError(t, err)
+
+		expected := strings.Join([]string{
+			"~ age: 30 -> 31",
+			`- nickname: "Al"`,
+			"  address",
+			`~   city: "NYC" -> "Boston"`,
+			"  labels",
+			`+   zone: "1"`,
+		}, "\n") + "\n"
+		assert.Equal(t, expected, out)
+	})
+
+	t.Run("from patch", func(t *testing.T) {
+		patch := map[string]any{
+			"a": map[string]any{
+				"b": map[string]any{"c": 1, "d": nil},
+				"e": "x",
+			},
+		}
+
+		out, err := Format(patch, FormatTree)
+		require.NoError(t, err)
+
+		expected := strings.Join([]string{
+			"  a",
+			"    b",
+			"~     c: 1",
+			"-     d",
+			`~   e: "x"`,
+		}, "\n") + "\n"
+		assert.Equal(t, expected, out)
+	})
+}
+
+func TestFormat_Color(t *testing.T) {
+	changes := []Change{{Path: []string{"age"}, Kind: Modified, Old: 30, New: 31}}
+
+	out, err := Format(changes, FormatColor)
+	require.NoError(t, err)
+	assert.Equal(t, "\x1b[31m- age: 30\x1b[0m\n\x1b[32m+ age: 31\x1b[0m\n", out)
+}
+
+func TestFormat_EdgeCases(t *testing.T) {
+	t.Run("no changes", func(t *testing.T) {
+		out, err := Format(map[string]any{}, FormatUnified)
+		require.NoError(t, err)
+		assert.Empty(t, out)
+
+		out, err = Format(nil, FormatTree)
+		require.NoError(t, err)
+		assert.Empty(t, out)
+	})
+
+	t.Run("ambiguous keys are quoted", func(t *testing.T) {
+		out, err := Format(map[string]any{"labels": map[string]any{"app.kubernetes.io/name": "web"}}, FormatUnified)
+		require.NoError(t, err)
+		assert.Equal(t, "+ labels[\"app.kubernetes.io/name\"]: \"web\"\n", out)
+	})
+
+	t.Run("root change", func(t *testing.T) {
+		changes, err := Changes(1, 2)
+		require.NoError(t, err)
+		out, err := Format(changes, FormatTree)
+		require.NoError(t, err)
+		assert.Equal(t, "~ (root): 1 -> 2\n", out)
+	})
+
+	t.Run("values that are not JSON", func(t *testing.T) {
+		out, err := Format([]Change{{Path: []string{"c"}, Kind: Added, New: complex(1, 2)}}, FormatUnified)
+		require.NoError(t, err)
+		assert.Equal(t, "+ c: (1+2i)\n", out)
+	})
+
+	t.Run("invalid input", func(t *testing.T) {
+		_, err := Format("nope", FormatUnified)
+		assert.Error(t, err)
+
+		_, err = Format(map[string]any{}, FormatStyle(99))
+		assert.Error(t, err)
+	})
+}
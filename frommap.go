@@ -0,0 +1,48 @@
+package structdiff
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// FromMap is the inverse of ToMap: it sets the struct pointed to by target
+// from a map in the form ToMap produces. The struct is zeroed first, then
+// each key is stored in the field with the matching JSON name, converting
+// values as ApplyToStruct does: nested maps become structs, pointers are
+// allocated, []any becomes typed slices and arrays, string map keys are parsed
+// back into the map's key type, and time.Time values are kept or parsed from
+// strings.
+//
+// For structs whose exported fields are of concrete types, FromMap(ToMap(x))
+// reproduces x. Interface fields receive the values of the map as is, so a
+// struct held by an interface comes back as a map[string]any; unexported
+// fields are left zero.
+//
+// Options are interpreted as by ApplyToStruct. Returns an error if target is
+// not a pointer to a struct or a value cannot be converted.
+func FromMap(m map[string]any, target any, opts ...Option) error {
+	targetVal := reflect.ValueOf(target)
+	if targetVal.Kind() != reflect.Pointer || targetVal.IsNil() {
+		return fmt.Errorf("target must be a non-nil pointer to a struct, got %T", target)
+	}
+	structVal := targetVal.Elem()
+	if structVal.Kind() != reflect.Struct {
+		return fmt.Errorf("target must point to a struct, got pointer to %s", structVal.Kind())
+	}
+
+	structVal.Set(reflect.Zero(structVal.Type()))
+
+	o := newOptions(opts)
+	o.building = true
+	return applyToStruct(target, m, o)
+}
+
+// FromMapOf is like FromMap, but returns a new value of the struct type T.
+func FromMapOf[T any](m map[string]any, opts ...Option) (T, error) {
+	var result T
+	if err := FromMap(m, &result, opts...); err != nil {
+		var zero T
+		return zero, err
+	}
+	return result, nil
+}
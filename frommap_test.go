@@ -0,0 +1,161 @@
+package structdiff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fromMapAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type fromMapLevel int
+
+type fromMapRecord struct {
+	ID        uint64                     `json:"id"`
+	Name      string                     `json:"name"`
+	Score     float32                    `json:"score"`
+	Active    bool                       `json:"active"`
+	Created   time.Time                  `json:"created"`
+	Updated   *time.Time                 `json:"updated"`
+	Address   fromMapAddress             `json:"address"`
+	Previous  *fromMapAddress            `json:"previous"`
+	Missing   *fromMapAddress            `json:"missing"`
+	Tags      []string                   `json:"tags"`
+	Empty     []int                      `json:"empty"`
+	Nil       []int                      `json:"nil"`
+	Data      []byte                     `json:"data"`
+	History   []fromMapAddress           `json:"history"`
+	Grid      [2][2]int                  `json:"grid"`
+	Counts    map[string]int             `json:"counts"`
+	ByID      map[int]*fromMapAddress    `json:"by_id"`
+	Levels    map[fromMapLevel][]string  `json:"levels"`
+	Extra     map[string]any             `json:"extra"`
+	Value     any                        `json:"value"`
+	Nested    map[string]map[string]bool `json:"nested"`
+	Ignored   string                     `json:"-"`
+	Untagged  int8
+	Level     fromMapLevel  `json:"level"`
+	Timeout   time.Duration `json:"timeout"`
+	PtrToPtr  **int         `json:"ptr_to_ptr"`
+	unexposed int
+}
+
+func newFromMapRecord() fromMapRecord {
+	created := time.Date(2024, 1, 2, 3, 4, 5, 6, time.UTC)
+	updated := created.Add(time.Hour)
+	n := 7
+	pn := &n
+	return fromMapRecord{
+		ID:       1<<63 + 1,
+		Name:     "record",
+		Score:    1.5,
+		Active:   true,
+		Created:  created,
+		Updated:  &updated,
+		Address:  fromMapAddress{Street: "1 Main St", City: "Springfield"},
+		Previous: &fromMapAddress{City: "Shelbyville"},
+		Tags:     []string{"a", "b"},
+		Empty:    []int{},
+		Data:     []byte("bytes"),
+		History:  []fromMapAddress{{City: "x"}, {City: "y"}},
+		Grid:     [2][2]int{{1, 2}, {3, 4}},
+		Counts:   map[string]int{"a": 1},
+		ByID:     map[int]*fromMapAddress{1: {City: "one"}, 2: nil},
+		Levels:   map[fromMapLevel][]string{3: {"x"}},
+		Extra:    map[string]any{"k": "v", "n": nil, "m": map[string]any{"x": 1}},
+		Value:    42,
+		Nested:   map[string]map[string]bool{"a": {"b": true}},
+		Untagged: -3,
+		Level:    2,
+		Timeout:  time.Second,
+		PtrToPtr: &pn,
+	}
+}
+
+func TestFromMap_RoundTrip(t *testing.T) {
+	original := newFromMapRecord()
+
+	var restored fromMapRecord
+	require.NoError(t, FromMap(ToMap(original), &restored))
+	assert.Equal(t, original, restored)
+}
+
+func TestFromMapOf(t *testing.T) {
+	original := newFromMapRecord()
+
+	restored, err := FromMapOf[fromMapRecord](ToMap(original))
+	require.NoError(t, err)
+	assert.Equal(t, original, restored)
+
+	_, err = FromMapOf[fromMapRecord](map[string]any{"id": "not a number"})
+	assert.Error(t, err)
+
+	_, err = FromMapOf[int](map[string]any{})
+	assert.Error(t, err)
+}
+
+func TestFromMap_ZeroesTarget(t *testing.T) {
+	target := fromMapRecord{Name: "old", Tags: []string{"old"}, Counts: map[string]int{"old": 1}, unexposed: 1}
+	require.NoError(t, FromMap(map[string]any{"counts": map[string]any{"new": 2}}, &target))
+	assert.Equal(t, fromMapRecord{Counts: map[string]int{"new": 2}}, target)
+
+	require.NoError(t, FromMap(nil, &target))
+	assert.Equal(t, fromMapRecord{}, target)
+}
+
+func TestFromMap_DoesNotAliasInput(t *testing.T) {
+	m := map[string]any{"extra": map[string]any{"k": "v"}}
+
+	var target fromMapRecord
+	require.NoError(t, FromMap(m, &target))
+	target.Extra["k"] = "changed"
+	assert.Equal(t, "v", m["extra"].(map[string]any)["k"])
+}
+
+func TestFromMap_Conversions(t *testing.T) {
+	m := map[string]any{
+		"id":      3.0,
+		"created": "2024-01-02T03:04:05Z",
+		"tags":    []any{"x"},
+		"counts":  map[string]any{"a": 2.0},
+	}
+
+	var target fromMapRecord
+	require.NoError(t, FromMap(m, &target))
+	assert.Equal(t, uint64(3), target.ID)
+	assert.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), target.Created)
+	assert.Equal(t, []string{"x"}, target.Tags)
+	assert.Equal(t, map[string]int{"a": 2}, target.Counts)
+}
+
+func TestFromMap_Errors(t *testing.T) {
+	var record fromMapRecord
+	n := 1
+
+	t.Run("not a pointer", func(t *testing.T) {
+		assert.ErrorContains(t, FromMap(nil, record), "non-nil pointer")
+	})
+
+	t.Run("nil pointer", func(t *testing.T) {
+		assert.ErrorContains(t, FromMap(nil, (*fromMapRecord)(nil)), "non-nil pointer")
+	})
+
+	t.Run("not a struct", func(t *testing.T) {
+		assert.ErrorContains(t, FromMap(nil, &n), "must point to a struct")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		assert.ErrorContains(t, FromMap(map[string]any{"bogus": 1}, &record), "bogus")
+	})
+
+	t.Run("bad value", func(t *testing.T) {
+		assert.ErrorContains(t, FromMap(map[string]any{"active": []any{}}, &record), "active")
+	})
+
+	assert.NoError(t, FromMap(map[string]any{"bogus": 1}, &record, IgnoreUnknownFields()))
+}
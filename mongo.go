@@ -0,0 +1,256 @@
+package structdiff
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MongoUpdate computes the differences between old and new, as DiffStructs
+// does, and returns them as a MongoDB update document:
+//
+//	{"$set": {"address.city": "Boston"}, "$unset": {"nickname": ""}}
+//
+// Changed values, including those within nested structs and maps, are set by
+// their dotted path, and removed values, such as pointers that became nil,
+// are unset. Values are taken from new as they are, so a nested struct
+// added whole is set as a struct, for the driver to encode. Field names come
+// from "bson" tags, or the tag chosen with NameTag, falling back to JSON
+// names. A map key that cannot appear in a dotted path, because it contains
+// a dot or starts with "$", makes the whole map be set instead.
+//
+// With ArrayUpdates, a slice that only grew at its end is updated with $push
+// and $each, and one that only lost some of its values with $pull and $in.
+//
+// Operators without changes are left out, so the document is empty if old
+// and new are equal.
+//
+// MongoUpdate takes old and new rather than a patch, as a patch tells neither
+// a nested patch from a map value added whole nor, with the old slice
+// missing, what $push and $pull should hold.
+func MongoUpdate(old, new any, opts ...Option) (map[string]any, error) {
+	o := newOptions(opts)
+	tag := o.nameTag
+	if tag == "" {
+		tag = "bson"
+	}
+
+	changes, err := Changes(old, new, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	set := make(map[string]any)
+	unset := make(map[string]any)
+	push := make(map[string]any)
+	pull := make(map[string]any)
+
+	root := reflect.TypeOf(new)
+	if root == nil {
+		root = reflect.TypeOf(old)
+	}
+
+	for _, change := range changes {
+		path := change.Path
+		// Map keys that are not valid in a dotted path make the map the
+		// unit of change
+		for i, key := range path {
+			if key == "" || strings.Contains(key, ".") || strings.HasPrefix(key, "$") {
+				path = path[:i]
+				break
+			}
+		}
+
+		if len(path) == 0 {
+			return nil, fmt.Errorf("cannot express change at %q as a field update", formatPath(change.Path))
+		}
+
+		names, ok := updatePath(root, path, tag)
+		if !ok {
+			continue
+		}
+		name := strings.Join(names, ".")
+
+		if change.Kind == Removed && len(path) == len(change.Path) {
+			unset[name] = ""
+			continue
+		}
+		newVal, err := Get(new, formatPointer(path))
+		if err != nil {
+			return nil, err
+		}
+		if isNilValue(reflect.ValueOf(newVal)) {
+			unset[name] = ""
+			continue
+		}
+
+		if o.arrayUpdates && len(path) == len(change.Path) {
+			oldVal, err := Get(old, formatPointer(path))
+			if err == nil {
+				if appended, ok := sliceAppended(oldVal, newVal); ok {
+					push[name] = map[string]any{"$each": appended}
+					continue
+				}
+				if removed, ok := sliceRemoved(oldVal, newVal); ok {
+					pull[name] = map[string]any{"$in": removed}
+					continue
+				}
+			}
+		}
+		set[name] = derefValue(newVal)
+	}
+
+	update := make(map[string]any)
+	for op, fields := range map[string]map[string]any{"$set": set, "$unset": unset, "$push": push, "$pull": pull} {
+		if len(fields) > 0 {
+			update[op] = fields
+		}
+	}
+	dropCoveredPaths(update)
+	return update, nil
+}
+
+// updatePath translates a path of JSON names within a value of type t into
+// the names given by tag. It returns false if a field on the way is tagged
+// "-". Below maps, slices and values of unknown type, keys are kept.
+func updatePath(t reflect.Type, path []string, tag string) ([]string, bool) {
+	names := make([]string, len(path))
+	for i, key := range path {
+		names[i] = key
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		if t == nil {
+			continue
+		}
+
+		switch t.Kind() {
+		case reflect.Struct:
+			_, field, err := findFieldByJSONName(t, key, &options{})
+			if err != nil {
+				t = nil
+				continue
+			}
+			value := field.Tag.Get(tag)
+			if value == "-" {
+				return nil, false
+			}
+			names[i] = parseName(value, key)
+			t = field.Type
+		case reflect.Map, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		default:
+			t = nil
+		}
+	}
+	return names, true
+}
+
+// sliceAppended returns the elements appended to old to give new, if new is
+// old followed by at least one more element.
+func sliceAppended(old, new any) ([]any, bool) {
+	oldVal, newVal := reflect.ValueOf(old), reflect.ValueOf(new)
+	if oldVal.Kind() != reflect.Slice || newVal.Kind() != reflect.Slice || newVal.Len() <= oldVal.Len() {
+		return nil, false
+	}
+	for i := 0; i < oldVal.Len(); i++ {
+		if !directValuesEqual(oldVal.Index(i), newVal.Index(i), false) {
+			return nil, false
+		}
+	}
+	appended := make([]any, 0, newVal.Len()-oldVal.Len())
+	for i := oldVal.Len(); i < newVal.Len(); i++ {
+		appended = append(appended, newVal.Index(i).Interface())
+	}
+	return appended, true
+}
+
+// sliceRemoved returns the distinct values removed from old to give new, if
+// new is old without every occurrence of those values, so that pulling them
+// gives new.
+func sliceRemoved(old, new any) ([]any, bool) {
+	oldVal, newVal := reflect.ValueOf(old), reflect.ValueOf(new)
+	if oldVal.Kind() != reflect.Slice || newVal.Kind() != reflect.Slice || newVal.Len() >= oldVal.Len() {
+		return nil, false
+	}
+
+	contains := func(list reflect.Value, v reflect.Value) bool {
+		for i := 0; i < list.Len(); i++ {
+			if directValuesEqual(list.Index(i), v, false) {
+				return true
+			}
+		}
+		return false
+	}
+
+	var removed []any
+	j := 0
+	for i := 0; i < oldVal.Len(); i++ {
+		elem := oldVal.Index(i)
+		if j < newVal.Len() && directValuesEqual(elem, newVal.Index(j), false) {
+			j++
+			continue
+		}
+		if contains(newVal, elem) {
+			// $pull would remove the occurrences that remain too
+			return nil, false
+		}
+		seen := false
+		for _, r := range removed {
+			seen = seen || valuesEqual(r, elem.Interface(), false)
+		}
+		if !seen {
+			removed = append(removed, elem.Interface())
+		}
+	}
+	return removed, j == newVal.Len()
+}
+
+// dropCoveredPaths removes the fields of an update document that lie below a
+// field that is set or unset whole, since MongoDB rejects updates of
+// conflicting paths.
+func dropCoveredPaths(update map[string]any) {
+	whole := make(map[string]bool)
+	for _, op := range []string{"$set", "$unset"} {
+		fields, _ := update[op].(map[string]any)
+		for name := range fields {
+			whole[name] = true
+		}
+	}
+
+	for op, fields := range update {
+		fields := fields.(map[string]any)
+		for name := range fields {
+			for i := strings.IndexByte(name, '.'); i >= 0; i = nextDot(name, i) {
+				if whole[name[:i]] {
+					delete(fields, name)
+					break
+				}
+			}
+		}
+		if len(fields) == 0 {
+			delete(update, op)
+		}
+	}
+}
+
+// nextDot returns the index of the first dot in s after index i, or -1.
+func nextDot(s string, i int) int {
+	j := strings.IndexByte(s[i+1:], '.')
+	if j < 0 {
+		return -1
+	}
+	return i + 1 + j
+}
+
+// derefValue returns the value a non-nil pointer points to, or v itself.
+func derefValue(v any) any {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return v
+	}
+	return rv.Interface()
+}
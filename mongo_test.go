@@ -0,0 +1,192 @@
+package structdiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type mongoAddress struct {
+	Street string `json:"street" bson:"street"`
+	City   string `json:"city" bson:"city_name"`
+}
+
+type mongoUser struct {
+	ID       string            `json:"id" bson:"_id"`
+	Name     string            `json:"name" bson:"name"`
+	Nickname *string           `json:"nickname" bson:"nick"`
+	Address  mongoAddress      `json:"address" bson:"addr"`
+	Work     *mongoAddress     `json:"work" bson:"work"`
+	Tags     []string          `json:"tags" bson:"tags"`
+	Labels   map[string]string `json:"labels" bson:"labels"`
+	Cache    string            `json:"cache" bson:"-"`
+	Untagged int               `json:"untagged"`
+}
+
+func newMongoUser() mongoUser {
+	nick := "annie"
+	return mongoUser{
+		ID:       "u1",
+		Name:     "Ann",
+		Nickname: &nick,
+		Address:  mongoAddress{Street: "Main", City: "Oslo"},
+		Tags:     []string{"a", "b", "c"},
+		Labels:   map[string]string{"team": "core"},
+	}
+}
+
+func TestMongoUpdate(t *testing.T) {
+	old := newMongoUser()
+	new := newMongoUser()
+	new.Name = "Anna"
+	new.Nickname = nil
+	new.Address.City = "Boston"
+	new.Work = &mongoAddress{City: "Cambridge"}
+	new.Labels = map[string]string{"team": "infra", "tier": "1"}
+	new.Cache = "ignored"
+	new.Untagged = 3
+
+	update, err := MongoUpdate(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"$set": map[string]any{
+			"name":           "Anna",
+			"addr.city_name": "Boston",
+			"work":           mongoAddress{City: "Cambridge"},
+			"labels.team":    "infra",
+			"labels.tier":    "1",
+			"untagged":       3,
+		},
+		"$unset": map[string]any{
+			"nick": "",
+		},
+	}, update)
+}
+
+func TestMongoUpdate_NoChanges(t *testing.T) {
+	update, err := MongoUpdate(newMongoUser(), newMongoUser())
+	require.NoError(t, err)
+	assert.Empty(t, update)
+}
+
+func TestMongoUpdate_NameTag(t *testing.T) {
+	type Doc struct {
+		Name string `json:"name" bson:"n" firestore:"display_name"`
+	}
+
+	update, err := MongoUpdate(Doc{Name: "a"}, Doc{Name: "b"}, NameTag("firestore"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"$set": map[string]any{"display_name": "b"}}, update)
+}
+
+func TestMongoUpdate_Slices(t *testing.T) {
+	t.Run("append", func(t *testing.T) {
+		old, new := newMongoUser(), newMongoUser()
+		new.Tags = []string{"a", "b", "c", "d", "e"}
+
+		update, err := MongoUpdate(old, new, ArrayUpdates())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"$push": map[string]any{"tags": map[string]any{"$each": []any{"d", "e"}}},
+		}, update)
+	})
+
+	t.Run("remove", func(t *testing.T) {
+		old, new := newMongoUser(), newMongoUser()
+		new.Tags = []string{"a", "c"}
+
+		update, err := MongoUpdate(old, new, ArrayUpdates())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"$pull": map[string]any{"tags": map[string]any{"$in": []any{"b"}}},
+		}, update)
+	})
+
+	t.Run("reorder", func(t *testing.T) {
+		old, new := newMongoUser(), newMongoUser()
+		new.Tags = []string{"c", "b", "a"}
+
+		update, err := MongoUpdate(old, new, ArrayUpdates())
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"$set": map[string]any{"tags": []string{"c", "b", "a"}}}, update)
+	})
+
+	t.Run("replaced without ArrayUpdates", func(t *testing.T) {
+		old, new := newMongoUser(), newMongoUser()
+		new.Tags = []string{"a", "b", "c", "d"}
+
+		update, err := MongoUpdate(old, new)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"$set": map[string]any{"tags": []string{"a", "b", "c", "d"}}}, update)
+	})
+}
+
+func TestMongoUpdate_PullNeedsAllOccurrencesRemoved(t *testing.T) {
+	old := mongoUser{Tags: []string{"a", "b", "a"}}
+	new := mongoUser{Tags: []string{"a", "b"}}
+
+	update, err := MongoUpdate(old, new, ArrayUpdates())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"$set": map[string]any{"tags": []string{"a", "b"}}}, update)
+
+	new.Tags = []string{"b"}
+	update, err = MongoUpdate(old, new, ArrayUpdates())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"$pull": map[string]any{"tags": map[string]any{"$in": []any{"a"}}}}, update)
+}
+
+func TestMongoUpdate_InvalidMapKeys(t *testing.T) {
+	old := newMongoUser()
+	new := newMongoUser()
+	new.Labels = map[string]string{"team": "infra", "app.io/name": "web", "$where": "x"}
+
+	update, err := MongoUpdate(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"$set": map[string]any{"labels": new.Labels},
+	}, update)
+
+	_, err = MongoUpdate(map[string]any{"a.b": 1}, map[string]any{"a.b": 2})
+	assert.ErrorContains(t, err, "cannot express change")
+}
+
+func TestMongoUpdate_Maps(t *testing.T) {
+	old := map[string]any{"a": map[string]any{"b": 1, "c": 2}, "gone": true}
+	new := map[string]any{"a": map[string]any{"b": 5, "c": 2}}
+
+	update, err := MongoUpdate(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"$set":   map[string]any{"a.b": 5},
+		"$unset": map[string]any{"gone": ""},
+	}, update)
+}
+
+func TestMongoUpdate_NilAndUnreachableValues(t *testing.T) {
+	type Doc struct {
+		Counts map[string]*int   `json:"counts"`
+		Grid   map[[2]int]string `json:"grid"`
+	}
+
+	update, err := MongoUpdate(Doc{Counts: map[string]*int{"a": intPtr(1)}}, Doc{Counts: map[string]*int{"a": nil}})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"$unset": map[string]any{"counts.a": ""}}, update)
+
+	// A changed value that cannot be looked up is an error, not an unset
+	_, err = MongoUpdate(Doc{Grid: map[[2]int]string{{1, 2}: "a"}}, Doc{Grid: map[[2]int]string{{1, 2}: "b"}})
+	assert.ErrorContains(t, err, "cannot convert map key")
+}
+
+func TestDropCoveredPaths(t *testing.T) {
+	update := map[string]any{
+		"$set":   map[string]any{"a": 1, "a.b": 2, "ab.c": 3},
+		"$unset": map[string]any{"x.y.z": ""},
+		"$push":  map[string]any{"x.y.z.w": 1},
+	}
+	dropCoveredPaths(update)
+	assert.Equal(t, map[string]any{
+		"$set":   map[string]any{"a": 1, "ab.c": 3},
+		"$unset": map[string]any{"x.y.z": ""},
+	}, update)
+}
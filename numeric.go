@@ -0,0 +1,169 @@
+package structdiff
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"reflect"
+	"strconv"
+)
+
+// numberKind classifies the numeric values compared by NormalizeNumbers.
+type numberKind int
+
+const (
+	notNumber numberKind = iota
+	intNumber
+	uintNumber
+	floatNumber
+	jsonNumber
+)
+
+// number is a numeric value of any Go numeric type, or a json.Number.
+type number struct {
+	kind numberKind
+	i    int64
+	u    uint64
+	f    float64
+	n    json.Number
+}
+
+// numberOf returns v as a number, if it is one.
+func numberOf(v reflect.Value) number {
+	if v.Type() == jsonNumberType {
+		return number{kind: jsonNumber, n: json.Number(v.String())}
+	}
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return number{kind: intNumber, i: v.Int()}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return number{kind: uintNumber, u: v.Uint()}
+	case reflect.Float32, reflect.Float64:
+		return number{kind: floatNumber, f: v.Float()}
+	}
+	return number{}
+}
+
+// numbersEqual reports whether two numbers have exactly the same value,
+// whatever their types: int(5), uint8(5), float64(5) and json.Number("5.0")
+// are equal, but int64(1<<53 + 1) and float64(1<<53) are not, and NaN is
+// equal to nothing.
+func numbersEqual(a, b number) bool {
+	if a.kind > b.kind {
+		a, b = b, a
+	}
+
+	switch {
+	case a.kind == intNumber && b.kind == intNumber:
+		return a.i == b.i
+	case a.kind == intNumber && b.kind == uintNumber:
+		return a.i >= 0 && uint64(a.i) == b.u
+	case a.kind == intNumber && b.kind == floatNumber:
+		return b.f == math.Trunc(b.f) && b.f >= -(1<<63) && b.f < 1<<63 && int64(b.f) == a.i
+	case a.kind == intNumber && b.kind == jsonNumber:
+		return jsonNumberIs(b.n, strconv.FormatInt(a.i, 10))
+	case a.kind == uintNumber && b.kind == uintNumber:
+		return a.u == b.u
+	case a.kind == uintNumber && b.kind == floatNumber:
+		return b.f == math.Trunc(b.f) && b.f >= 0 && b.f < 1<<64 && uint64(b.f) == a.u
+	case a.kind == uintNumber && b.kind == jsonNumber:
+		return jsonNumberIs(b.n, strconv.FormatUint(a.u, 10))
+	case a.kind == floatNumber && b.kind == floatNumber:
+		return a.f == b.f
+	case a.kind == floatNumber && b.kind == jsonNumber:
+		return jsonNumberIsFloat(b.n, a.f)
+	case a.kind == jsonNumber && b.kind == jsonNumber:
+		return jsonNumbersEqual(a.n, b.n)
+	}
+	return false
+}
+
+// jsonNumberIs reports whether n has the value of the decimal integer s.
+func jsonNumberIs(n json.Number, s string) bool {
+	if string(n) == s {
+		return true
+	}
+	d, ok := parseDecimal(string(n))
+	if !ok {
+		return false
+	}
+	i, ok := d.integer(20)
+	return ok && i == s
+}
+
+// jsonNumberIsFloat reports whether n has exactly the value of f, which holds
+// only if n is the full decimal expansion of f, not just a decimal that rounds
+// to it.
+func jsonNumberIsFloat(n json.Number, f float64) bool {
+	if math.IsInf(f, 0) || math.IsNaN(f) {
+		return false
+	}
+	d, ok := parseDecimal(string(n))
+	if !ok {
+		return false
+	}
+	if d.digits == "" || f == 0 {
+		return d.digits == "" && f == 0
+	}
+	// Nonzero float64 values lie between 1e-324 and 1e309; checking the
+	// exponent first keeps big.Rat from expanding huge powers of ten
+	if d.exp < -324 || d.exp > 309 {
+		return false
+	}
+	r, ok := new(big.Rat).SetString(string(n))
+	return ok && r.Cmp(new(big.Rat).SetFloat64(f)) == 0
+}
+
+// looseValuesEqual compares two values of different types for
+// NormalizeNumbers: numbers by value, and slices, arrays and maps with the
+// same key type element by element, so that []int{1} equals []any{1.0}.
+// Other values of different types are not equal.
+func looseValuesEqual(a, b reflect.Value, visited map[visitPair]bool) bool {
+	if a.Kind() == reflect.Interface || b.Kind() == reflect.Interface {
+		if a.Kind() == reflect.Interface {
+			a = a.Elem()
+		}
+		if b.Kind() == reflect.Interface {
+			b = b.Elem()
+		}
+		return deepValuesEqual(a, b, true, visited)
+	}
+
+	if numA := numberOf(a); numA.kind != notNumber {
+		numB := numberOf(b)
+		return numB.kind != notNumber && numbersEqual(numA, numB)
+	}
+
+	isList := func(v reflect.Value) bool {
+		return v.Kind() == reflect.Slice || v.Kind() == reflect.Array
+	}
+	switch {
+	case isList(a) && isList(b):
+		if (a.Kind() == reflect.Slice && a.IsNil()) != (b.Kind() == reflect.Slice && b.IsNil()) {
+			return false
+		}
+		if a.Len() != b.Len() {
+			return false
+		}
+		for i := 0; i < a.Len(); i++ {
+			if !deepValuesEqual(a.Index(i), b.Index(i), true, visited) {
+				return false
+			}
+		}
+		return true
+
+	case a.Kind() == reflect.Map && b.Kind() == reflect.Map && a.Type().Key() == b.Type().Key():
+		if a.IsNil() != b.IsNil() || a.Len() != b.Len() {
+			return false
+		}
+		iter := a.MapRange()
+		for iter.Next() {
+			bVal := b.MapIndex(iter.Key())
+			if !bVal.IsValid() || !deepValuesEqual(iter.Value(), bVal, true, visited) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
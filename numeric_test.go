@@ -0,0 +1,135 @@
+package structdiff
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNumbersEqual(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  any
+		equal bool
+	}{
+		{"int and float", 5, 5.0, true},
+		{"int and uint8", int8(-1), uint8(255), false},
+		{"uint and int", uint(7), int64(7), true},
+		{"float32 and int", float32(2.5), 2, false},
+		{"fraction", 5, 5.5, false},
+		{"beyond float precision", int64(1<<53 + 1), float64(1 << 53), false},
+		{"large uint and float", uint64(1 << 63), float64(1 << 63), true},
+		{"float out of int range", int64(math.MaxInt64), float64(1 << 63), false},
+		{"json and int", json.Number("5.0"), 5, true},
+		{"json and large uint", json.Number("18446744073709551615"), uint64(math.MaxUint64), true},
+		{"json and float", json.Number("0.5"), 0.5, true},
+		{"json and inexact float", json.Number("0.1"), 0.1, false},
+		{"json zero and negative zero", json.Number("0"), math.Copysign(0, -1), true},
+		{"json huge exponent", json.Number("1e999999999"), math.MaxFloat64, false},
+		{"NaN", math.NaN(), math.NaN(), false},
+		{"json and json", json.Number("1e2"), json.Number("100"), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := numberOf(reflect.ValueOf(tt.a)), numberOf(reflect.ValueOf(tt.b))
+			require.NotEqual(t, notNumber, a.kind)
+			require.NotEqual(t, notNumber, b.kind)
+			assert.Equal(t, tt.equal, numbersEqual(a, b))
+			assert.Equal(t, tt.equal, numbersEqual(b, a))
+		})
+	}
+}
+
+func TestDiffMaps_NormalizeNumbers(t *testing.T) {
+	live := map[string]any{
+		"replicas": 3,
+		"ratio":    0.5,
+		"ports":    []int{80, 443},
+		"tags":     []string{"a", "b"},
+		"limits":   map[string]int{"cpu": 2},
+		"nested":   map[string]any{"id": uint64(42)},
+	}
+	file := map[string]any{
+		"replicas": 3.0,
+		"ratio":    json.Number("0.5"),
+		"ports":    []any{80.0, 443.0},
+		"tags":     []any{"a", "b"},
+		"limits":   map[string]any{"cpu": 2.0},
+		"nested":   map[string]any{"id": 42.0},
+	}
+
+	patch, err := DiffMaps(live, file, NormalizeNumbers())
+	require.NoError(t, err)
+	assert.Empty(t, patch)
+
+	// Without the option every key differs in type
+	patch, err = DiffMaps(live, file)
+	require.NoError(t, err)
+	assert.Len(t, patch, 6)
+}
+
+func TestDiffMaps_NormalizeNumbersChanges(t *testing.T) {
+	old := map[string]any{"a": 1, "b": []int{1, 2}, "c": []string{"x"}}
+	new := map[string]any{"a": 1.5, "b": []any{1.0, 3.0}, "c": []any{"x", 1}}
+
+	patch, err := DiffMaps(old, new, NormalizeNumbers())
+	require.NoError(t, err)
+	assert.Equal(t, new, patch)
+}
+
+func TestDiff_NormalizeNumbers(t *testing.T) {
+	type Config struct {
+		Name  string         `json:"name"`
+		Value any            `json:"value"`
+		Extra map[string]any `json:"extra"`
+	}
+
+	old := Config{Name: "a", Value: 5, Extra: map[string]any{"n": int32(1), "l": []int{1}}}
+	new := Config{Name: "a", Value: 5.0, Extra: map[string]any{"n": json.Number("1"), "l": []any{1.0}}}
+
+	patch, err := Diff(old, new, NormalizeNumbers())
+	require.NoError(t, err)
+	assert.Empty(t, patch)
+	assert.True(t, Equal(old, new, NormalizeNumbers()))
+	assert.False(t, Equal(old, new))
+
+	// Structs compared with maps
+	patch, err = Diff(old, map[string]any{"name": "a", "value": 5.0, "extra": map[string]any{"n": 1.0, "l": []any{1.0}}}, NormalizeNumbers())
+	require.NoError(t, err)
+	assert.Empty(t, patch)
+
+	new.Value = 6.0
+	patch, err = Diff(old, new, NormalizeNumbers())
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{"value": 6.0}, patch)
+}
+
+func TestLooseValuesEqual_Collections(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  any
+		equal bool
+	}{
+		{"slice and array", []int{1, 2}, [2]float64{1, 2}, true},
+		{"different lengths", []int{1}, []any{1.0, 2.0}, false},
+		{"nil and empty", []int(nil), []any{}, false},
+		{"nested", [][]int{{1}}, []any{[]any{1.0}}, true},
+		{"different key types", map[int]any{1: 1}, map[string]any{"1": 1}, false},
+		{"map values", map[string]int{"a": 1}, map[string]float64{"a": 1}, true},
+		{"missing key", map[string]int{"a": 1}, map[string]float64{"b": 1}, false},
+		{"number and string", 1, "1", false},
+		{"string types", "a", json.Number("1"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.equal, valuesEqual(tt.a, tt.b, true))
+			assert.Equal(t, tt.equal, valuesEqual(tt.b, tt.a, true))
+		})
+	}
+}
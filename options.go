@@ -0,0 +1,243 @@
+package structdiff
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Option configures the behavior of the diff and apply functions.
+// Options that do not apply to a particular operation are ignored by it.
+type Option func(*options)
+
+// options holds the resolved configuration for a single operation, along with
+// any state that must be shared across its recursive calls.
+type options struct {
+	// Diff options
+	arrayIndexDiff   bool
+	normalizeNumbers bool
+
+	// ToMap options, which shape ToMap's output only. The diff functions
+	// always convert structs to maps in the default form.
+	omitZero    bool
+	flattenKeys bool
+	maxDepth    int
+	timeRFC3339 bool
+	shapeMap    bool // set by ToMap: apply the ToMap options
+	depth       int  // nesting depth of the value ToMap converts
+
+	// Update document options
+	nameTag      string
+	arrayUpdates bool
+
+	// Field mask options
+	snakeCaseType reflect.Type
+
+	// Redaction options
+	redactPaths [][]string
+	redactSalt  []byte
+
+	// Traversal options and state
+	cycleMode  CycleMode
+	visitStack map[visitKey]bool
+	sortKeys   bool     // visit map keys in sorted order
+	path       []string // path of the value ToMap converts, tracked when redacting by path
+
+	// Apply options
+	ignoreUnknownFields bool
+	noStringCoercion    bool
+	noWeakTyping        bool
+	caseInsensitive     bool
+	building            bool // set by FromMap: nil map values are zero values, not deletions
+}
+
+// newOptions resolves a list of Option values into an options struct.
+func newOptions(opts []Option) *options {
+	o := &options{}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(o)
+		}
+	}
+	return o
+}
+
+// DiffArraysByIndex makes the diff functions emit per-index changes for
+// fixed-size arrays instead of the whole new array. Since an array's length
+// never changes, the patch is a map keyed by the decimal index of each changed
+// element, e.g. {"2": 1.5}. Such patches are understood by ApplyToStruct and
+// ApplyToMap.
+func DiffArraysByIndex() Option {
+	return func(o *options) {
+		o.arrayIndexDiff = true
+	}
+}
+
+// NormalizeNumbers makes the diff functions and Equal compare numbers by
+// value whatever their types, so that int(5) from a Go literal equals
+// float64(5) from decoded JSON and json.Number("5.0"). Comparisons are exact:
+// int64(1<<53 + 1) differs from float64(1<<53), and json.Number("0.1")
+// differs from float64(0.1), which is not exactly one tenth.
+//
+// Slices, arrays and maps of different types are compared element by
+// element, so []string{"a"} equals []any{"a"} and []int{1} equals
+// []any{1.0}. Values that are not equal are reported with their new value as
+// usual.
+func NormalizeNumbers() Option {
+	return func(o *options) {
+		o.normalizeNumbers = true
+	}
+}
+
+// OnCycle selects how ToMap and the diff functions handle pointer cycles.
+// The default is CycleError.
+func OnCycle(mode CycleMode) Option {
+	return func(o *options) {
+		o.cycleMode = mode
+	}
+}
+
+// DisallowUnknownFields makes ApplyToStruct return an error when the patch
+// contains a key that does not match any field of the target struct.
+// This is the default behavior.
+func DisallowUnknownFields() Option {
+	return func(o *options) {
+		o.ignoreUnknownFields = false
+	}
+}
+
+// IgnoreUnknownFields makes ApplyToStruct silently skip patch keys that do
+// not match any field of the target struct.
+func IgnoreUnknownFields() Option {
+	return func(o *options) {
+		o.ignoreUnknownFields = true
+	}
+}
+
+// NoStringCoercion makes ApplyToStruct reject non-string values for string
+// fields instead of formatting them with %v (e.g. int → string is an error).
+// []byte values are still accepted.
+func NoStringCoercion() Option {
+	return func(o *options) {
+		o.noStringCoercion = true
+	}
+}
+
+// NoWeakTyping makes ApplyToStruct reject conversions that reinterpret a value
+// rather than merely changing its representation:
+// - strings to numbers or bools ("42" → int, "true" → bool)
+// - numbers to bools (1 → true)
+//
+// Conversions between numeric types (e.g. float64 → int) remain allowed, since
+// that is how decoded JSON numbers reach integer fields.
+func NoWeakTyping() Option {
+	return func(o *options) {
+		o.noWeakTyping = true
+	}
+}
+
+// CaseInsensitiveFields makes ApplyToStruct match patch keys to field names
+// case-insensitively when there is no exact match, like encoding/json does.
+func CaseInsensitiveFields() Option {
+	return func(o *options) {
+		o.caseInsensitive = true
+	}
+}
+
+// Redact redacts the values at the given dotted paths, and everything below
+// them, like fields tagged `diff:"redact"`: ToMap and the diff functions emit
+// a Redacted placeholder instead of the value, and reports show it. Paths use
+// the same keys as patches, e.g. "user.password", with dots and backslashes
+// within keys escaped with a backslash as in Flatten, e.g. `labels.app\.io`.
+// A path with an invalid escape is split at every dot, as it is written.
+func Redact(paths ...string) Option {
+	return func(o *options) {
+		for _, p := range paths {
+			keys, err := splitEscapedPath(p)
+			if err != nil {
+				keys = strings.Split(p, ".")
+			}
+			o.redactPaths = append(o.redactPaths, keys)
+		}
+	}
+}
+
+// RedactWithHash makes redacted values appear as a salted hash of the value
+// instead of RedactedPlaceholder, so that a change can be told apart from
+// another without revealing either value. See Redacted.
+func RedactWithHash(salt []byte) Option {
+	return func(o *options) {
+		o.redactSalt = salt
+	}
+}
+
+// OmitZero makes ToMap leave out struct fields and map entries holding the
+// zero value of their type, such as 0, "", false, nil and zero structs. Empty
+// but non-nil slices and maps are kept.
+func OmitZero() Option {
+	return func(o *options) {
+		o.omitZero = true
+	}
+}
+
+// FlattenKeys makes ToMap return a single-level map, in which values of
+// nested structs and maps are keyed by their dotted path, e.g.
+// "address.city". Dots and backslashes within keys are escaped with a
+// backslash, so "a.b" as a single key becomes "a\.b". Slices and empty
+// nested maps are kept as values.
+func FlattenKeys() Option {
+	return func(o *options) {
+		o.flattenKeys = true
+	}
+}
+
+// MaxDepth makes ToMap convert only the top depth levels of nesting: values
+// found depth keys below the top are stored as they are, without converting
+// structs, slices or maps within them. Zero, the default, means no limit.
+// Values that may hold redacted values are still converted, so that these
+// are replaced.
+func MaxDepth(depth int) Option {
+	return func(o *options) {
+		o.maxDepth = depth
+	}
+}
+
+// TimeAsRFC3339 makes ToMap store time.Time values as RFC 3339 strings, with
+// fractional seconds when they are not zero, instead of time.Time values.
+// ApplyToStruct and FromMap parse such strings back.
+func TimeAsRFC3339() Option {
+	return func(o *options) {
+		o.timeRFC3339 = true
+	}
+}
+
+// NameTag sets the struct tag the update generators take field names from,
+// instead of their default: "bson" for MongoUpdate and "dynamodbav" for
+// DynamoUpdate. Fields without the tag keep their JSON name, and fields
+// tagged "-" are left out.
+func NameTag(tag string) Option {
+	return func(o *options) {
+		o.nameTag = tag
+	}
+}
+
+// SnakeCaseNames makes FieldMaskPaths write the names of the fields of v's
+// type in snake_case, as protobuf field masks use, instead of as JSON names:
+// "homeAddress" becomes "home_address". v is a value, or a pointer to one, of
+// the type the patch was computed from, which tells fields from map keys;
+// map keys are kept as they are. ApplyWithMask accepts either form without
+// it.
+func SnakeCaseNames(v any) Option {
+	return func(o *options) {
+		o.snakeCaseType = reflect.TypeOf(v)
+	}
+}
+
+// ArrayUpdates makes the update generators express a slice that only grew at
+// its end, or only lost some of its values, as an array operation instead of
+// replacing it: $push with $each or $pull with $in for MongoUpdate, and
+// list_append or REMOVE by index for DynamoUpdate.
+func ArrayUpdates() Option {
+	return func(o *options) {
+		o.arrayUpdates = true
+	}
+}
@@ -0,0 +1,136 @@
+package structdiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyToStruct_UnknownFields(t *testing.T) {
+	t.Run("disallowed by default", func(t *testing.T) {
+		target := &TestStruct{}
+		err := ApplyToStruct(target, map[string]any{"name": "John", "nonexistent": "value"})
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "field \"nonexistent\" not found")
+	})
+
+	t.Run("explicitly disallowed", func(t *testing.T) {
+		target := &TestStruct{}
+		err := ApplyToStruct(target, map[string]any{"nonexistent": "value"}, IgnoreUnknownFields(), DisallowUnknownFields())
+		assert.Error(t, err)
+	})
+
+	t.Run("ignored", func(t *testing.T) {
+		target := &TestStruct{}
+		err := ApplyToStruct(target, map[string]any{"name": "John", "nonexistent": "value"}, IgnoreUnknownFields())
+		require.NoError(t, err)
+		assert.Equal(t, "John", target.Name)
+	})
+
+	t.Run("ignored in nested structs", func(t *testing.T) {
+		target := &NestedTestStruct{}
+		patch := map[string]any{
+			"user": map[string]any{"name": "John", "nickname": "JJ"},
+		}
+		err := ApplyToStruct(target, patch, IgnoreUnknownFields())
+		require.NoError(t, err)
+		assert.Equal(t, "John", target.User.Name)
+	})
+
+	t.Run("passed through Apply", func(t *testing.T) {
+		target := &TestStruct{}
+		err := Apply(target, map[string]any{"nonexistent": "value"}, IgnoreUnknownFields())
+		assert.NoError(t, err)
+	})
+}
+
+func TestApplyToStruct_NoStringCoercion(t *testing.T) {
+	t.Run("coerces by default", func(t *testing.T) {
+		target := &TestStruct{}
+		err := ApplyToStruct(target, map[string]any{"name": 42})
+		require.NoError(t, err)
+		assert.Equal(t, "42", target.Name)
+	})
+
+	t.Run("rejects non-string values", func(t *testing.T) {
+		target := &TestStruct{}
+		err := ApplyToStruct(target, map[string]any{"name": 42}, NoStringCoercion())
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "cannot convert int to string")
+	})
+
+	t.Run("still accepts strings and bytes", func(t *testing.T) {
+		target := &TestStruct{}
+		err := ApplyToStruct(target, map[string]any{"name": "John", "email": []byte("j@example.com")}, NoStringCoercion())
+		require.NoError(t, err)
+		assert.Equal(t, "John", target.Name)
+		assert.Equal(t, "j@example.com", target.Email)
+	})
+}
+
+func TestApplyToStruct_NoWeakTyping(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch map[string]any
+	}{
+		{"string to bool", map[string]any{"active": "true"}},
+		{"int to bool", map[string]any{"active": 1}},
+		{"float to bool", map[string]any{"active": 1.0}},
+		{"string to int", map[string]any{"age": "42"}},
+		{"string to float", map[string]any{"score": "95.5"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.NoError(t, ApplyToStruct(&TestStruct{}, tt.patch))
+
+			err := ApplyToStruct(&TestStruct{}, tt.patch, NoWeakTyping())
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("numeric conversions still allowed", func(t *testing.T) {
+		target := &TestStruct{}
+		err := ApplyToStruct(target, map[string]any{"age": float64(42), "score": 95, "active": true}, NoWeakTyping())
+		require.NoError(t, err)
+		assert.Equal(t, 42, target.Age)
+		assert.Equal(t, 95.0, target.Score)
+		assert.True(t, target.Active)
+	})
+}
+
+func TestApplyToStruct_CaseInsensitiveFields(t *testing.T) {
+	type MixedCase struct {
+		UserName string `json:"userName"`
+		Username string `json:"username"`
+		Email    string
+	}
+
+	t.Run("case-sensitive by default", func(t *testing.T) {
+		target := &MixedCase{}
+		err := ApplyToStruct(target, map[string]any{"EMAIL": "a@b.c"})
+		assert.Error(t, err)
+	})
+
+	t.Run("matches case-insensitively", func(t *testing.T) {
+		target := &MixedCase{}
+		err := ApplyToStruct(target, map[string]any{"EMAIL": "a@b.c"}, CaseInsensitiveFields())
+		require.NoError(t, err)
+		assert.Equal(t, "a@b.c", target.Email)
+	})
+
+	t.Run("exact match preferred", func(t *testing.T) {
+		target := &MixedCase{}
+		err := ApplyToStruct(target, map[string]any{"username": "lower"}, CaseInsensitiveFields())
+		require.NoError(t, err)
+		assert.Equal(t, "lower", target.Username)
+		assert.Equal(t, "", target.UserName)
+	})
+
+	t.Run("passed through ApplyToMap to struct values", func(t *testing.T) {
+		original := map[string]any{"user": MixedCase{Email: "old"}}
+		result := ApplyToMap(original, map[string]any{"user": map[string]any{"email": "new"}}, CaseInsensitiveFields())
+		assert.Equal(t, MixedCase{Email: "new"}, result["user"])
+	})
+}
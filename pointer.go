@@ -0,0 +1,350 @@
+package structdiff
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Get returns the value at an RFC 6901 JSON Pointer within v, which may be a
+// struct, a map, or a pointer to either. Each reference token of the pointer
+// selects a struct field by its JSON name, a map entry by its key, parsed
+// into the map's key type as ApplyToStruct does, or a slice or array element
+// by its index. The empty pointer "" refers to v itself.
+//
+// The value is returned as stored, without conversion: a struct field of type
+// *Address gives a *Address. Returns an error if the pointer is malformed or
+// does not refer to an existing value.
+func Get(v any, ptr string, opts ...Option) (any, error) {
+	tokens, err := parsePointer(ptr)
+	if err != nil {
+		return nil, err
+	}
+	o := newOptions(opts)
+
+	cur := reflect.ValueOf(v)
+	for _, token := range tokens {
+		if cur, err = pointerChild(cur, token, o); err != nil {
+			return nil, fmt.Errorf("pointer %q: %w", ptr, err)
+		}
+	}
+	if !cur.IsValid() || !cur.CanInterface() {
+		return nil, nil
+	}
+	return cur.Interface(), nil
+}
+
+// Set sets the value at an RFC 6901 JSON Pointer within the struct or map
+// target points to, resolved as by Get. The value is converted to the type
+// of its destination as by FromMap, so a map[string]any can be stored in a
+// struct field, replacing its previous value. A nil value sets a pointer,
+// slice, map or interface to nil.
+//
+// Nil pointers and maps on the way are allocated, and a new map key may be
+// added. The token "-" appends to a slice. Returns an error if the pointer
+// is malformed or its parent does not exist, or if the value cannot be
+// converted.
+func Set(target any, ptr string, value any, opts ...Option) error {
+	tokens, err := parsePointer(ptr)
+	if err != nil {
+		return err
+	}
+	v, err := pointerTarget(target)
+	if err != nil {
+		return err
+	}
+	o := newOptions(opts)
+	o.building = true
+
+	if len(tokens) == 0 {
+		return assignValue(v, value, ptr, o)
+	}
+
+	err = walkPointer(v, tokens, true, o, func(container reflect.Value, token string) error {
+		switch container.Kind() {
+		case reflect.Struct:
+			index, _, err := findFieldByJSONName(container.Type(), token, o)
+			if err != nil {
+				return err
+			}
+			return assignValue(container.Field(index), value, token, o)
+
+		case reflect.Map:
+			if container.IsNil() {
+				container.Set(reflect.MakeMap(container.Type()))
+			}
+			key, err := convertMapKey(reflect.ValueOf(token), container.Type().Key(), token, o)
+			if err != nil {
+				return err
+			}
+			elem := reflect.New(container.Type().Elem()).Elem()
+			if err := assignValue(elem, value, token, o); err != nil {
+				return err
+			}
+			container.SetMapIndex(key, elem)
+			return nil
+
+		case reflect.Slice:
+			if token == "-" {
+				elem := reflect.New(container.Type().Elem()).Elem()
+				if err := assignValue(elem, value, token, o); err != nil {
+					return err
+				}
+				container.Set(reflect.Append(container, elem))
+				return nil
+			}
+			fallthrough
+
+		case reflect.Array:
+			i, err := parseIndex(token, container.Len())
+			if err != nil {
+				return err
+			}
+			return assignValue(container.Index(i), value, token, o)
+		}
+		return fmt.Errorf("cannot index %s with %q", container.Type(), token)
+	})
+	if err != nil {
+		return fmt.Errorf("pointer %q: %w", ptr, err)
+	}
+	return nil
+}
+
+// Delete removes the value at an RFC 6901 JSON Pointer within the struct or
+// map target points to, resolved as by Get. Map entries are deleted, slice
+// elements are removed, shifting the ones after them, and struct fields,
+// which cannot be removed, are set to their zero value. Returns an error if
+// the pointer is malformed or empty, does not refer to an existing value, or
+// refers to an array element.
+func Delete(target any, ptr string, opts ...Option) error {
+	tokens, err := parsePointer(ptr)
+	if err != nil {
+		return err
+	}
+	if len(tokens) == 0 {
+		return fmt.Errorf("cannot delete the whole value")
+	}
+	v, err := pointerTarget(target)
+	if err != nil {
+		return err
+	}
+	o := newOptions(opts)
+
+	err = walkPointer(v, tokens, false, o, func(container reflect.Value, token string) error {
+		switch container.Kind() {
+		case reflect.Struct:
+			index, _, err := findFieldByJSONName(container.Type(), token, o)
+			if err != nil {
+				return err
+			}
+			field := container.Field(index)
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+
+		case reflect.Map:
+			key, err := convertMapKey(reflect.ValueOf(token), container.Type().Key(), token, o)
+			if err != nil {
+				return err
+			}
+			if !container.MapIndex(key).IsValid() {
+				return fmt.Errorf("key %q not found", token)
+			}
+			container.SetMapIndex(key, reflect.Value{})
+			return nil
+
+		case reflect.Slice:
+			i, err := parseIndex(token, container.Len())
+			if err != nil {
+				return err
+			}
+			// Build a new slice rather than shifting the elements within
+			// the backing array, which other slices may share
+			n := container.Len()
+			result := reflect.MakeSlice(container.Type(), n-1, n-1)
+			reflect.Copy(result, container.Slice(0, i))
+			reflect.Copy(result.Slice(i, n-1), container.Slice(i+1, n))
+			container.Set(result)
+			return nil
+
+		case reflect.Array:
+			return fmt.Errorf("cannot delete an element of array %s", container.Type())
+		}
+		return fmt.Errorf("cannot index %s with %q", container.Type(), token)
+	})
+	if err != nil {
+		return fmt.Errorf("pointer %q: %w", ptr, err)
+	}
+	return nil
+}
+
+// parsePointer splits an RFC 6901 JSON Pointer into its unescaped reference
+// tokens.
+func parsePointer(ptr string) ([]string, error) {
+	if ptr == "" {
+		return nil, nil
+	}
+	if !strings.HasPrefix(ptr, "/") {
+		return nil, fmt.Errorf("invalid JSON pointer %q: must be empty or start with /", ptr)
+	}
+
+	tokens := strings.Split(ptr[1:], "/")
+	for i, token := range tokens {
+		for j := 0; j < len(token); j++ {
+			if token[j] == '~' && (j+1 == len(token) || (token[j+1] != '0' && token[j+1] != '1')) {
+				return nil, fmt.Errorf("invalid JSON pointer %q: bad escape in %q", ptr, token)
+			}
+		}
+		// ~1 must be replaced before ~0, so that "~01" becomes "~1"
+		tokens[i] = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+	}
+	return tokens, nil
+}
+
+// parseIndex parses an array index token of a JSON Pointer, which must be a
+// decimal number without leading zeros, less than n.
+func parseIndex(token string, n int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') || !isDigits(token) {
+		return 0, fmt.Errorf("invalid index %q", token)
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i >= n {
+		return 0, fmt.Errorf("index %s out of range for length %d", token, n)
+	}
+	return i, nil
+}
+
+// pointerTarget returns the value target points to, which Set and Delete
+// modify.
+func pointerTarget(target any) (reflect.Value, error) {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Pointer || v.IsNil() {
+		return reflect.Value{}, fmt.Errorf("target must be a non-nil pointer, got %T", target)
+	}
+	return v.Elem(), nil
+}
+
+// pointerChild returns the value a reference token selects within v,
+// looking through pointers and interfaces.
+func pointerChild(v reflect.Value, token string, o *options) (reflect.Value, error) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return reflect.Value{}, fmt.Errorf("no value at %q: parent is nil", token)
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		index, _, err := findFieldByJSONName(v.Type(), token, o)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return v.Field(index), nil
+
+	case reflect.Map:
+		key, err := convertMapKey(reflect.ValueOf(token), v.Type().Key(), token, o)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		elem := v.MapIndex(key)
+		if !elem.IsValid() {
+			return reflect.Value{}, fmt.Errorf("key %q not found", token)
+		}
+		return elem, nil
+
+	case reflect.Slice, reflect.Array:
+		i, err := parseIndex(token, v.Len())
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		return v.Index(i), nil
+	}
+
+	if !v.IsValid() {
+		return reflect.Value{}, fmt.Errorf("no value at %q", token)
+	}
+	return reflect.Value{}, fmt.Errorf("cannot index %s with %q", v.Type(), token)
+}
+
+// walkPointer follows tokens from the settable value v down to the container
+// of the value the last token refers to, and calls leaf with that container
+// and token. Pointers are followed, and allocated if nil and create is set.
+// Map entries and the dynamic values of interfaces, which cannot be modified
+// in place, are copied, modified and stored back.
+func walkPointer(v reflect.Value, tokens []string, create bool, o *options, leaf func(container reflect.Value, token string) error) error {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			if !create {
+				return fmt.Errorf("no value at %q: parent is nil", tokens[0])
+			}
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return fmt.Errorf("no value at %q: parent is nil", tokens[0])
+		}
+		elem := reflect.New(v.Elem().Type()).Elem()
+		elem.Set(v.Elem())
+		if err := walkPointer(elem, tokens, create, o, leaf); err != nil {
+			return err
+		}
+		v.Set(elem)
+		return nil
+	}
+
+	if len(tokens) == 1 {
+		return leaf(v, tokens[0])
+	}
+
+	if v.Kind() == reflect.Map {
+		key, err := convertMapKey(reflect.ValueOf(tokens[0]), v.Type().Key(), tokens[0], o)
+		if err != nil {
+			return err
+		}
+		existing := v.MapIndex(key)
+		if !existing.IsValid() {
+			return fmt.Errorf("key %q not found", tokens[0])
+		}
+		elem := reflect.New(existing.Type()).Elem()
+		elem.Set(existing)
+		if err := walkPointer(elem, tokens[1:], create, o, leaf); err != nil {
+			return err
+		}
+		v.SetMapIndex(key, elem)
+		return nil
+	}
+
+	child, err := pointerChild(v, tokens[0], o)
+	if err != nil {
+		return err
+	}
+	return walkPointer(child, tokens[1:], create, o, leaf)
+}
+
+// assignValue replaces the settable value v with value, converted to its
+// type.
+func assignValue(v reflect.Value, value any, name string, o *options) error {
+	if value == nil {
+		return setFieldToNil(v, name)
+	}
+	newVal := reflect.New(v.Type()).Elem()
+	if err := applyValuePatch(newVal, value, name, o); err != nil {
+		return err
+	}
+	v.Set(newVal)
+	return nil
+}
+
+// formatPointer returns the JSON Pointer for a path of unescaped keys.
+func formatPointer(path []string) string {
+	var b strings.Builder
+	for _, key := range path {
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(strings.ReplaceAll(key, "~", "~0"), "/", "~1"))
+	}
+	return b.String()
+}
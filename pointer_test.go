@@ -0,0 +1,319 @@
+package structdiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type pointerAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type pointerPerson struct {
+	Name    string                     `json:"name"`
+	Age     int                        `json:"age"`
+	Address pointerAddress             `json:"address"`
+	Work    *pointerAddress            `json:"work"`
+	Tags    []string                   `json:"tags"`
+	Scores  [3]int                     `json:"scores"`
+	ByID    map[int]pointerAddress     `json:"by_id"`
+	Labels  map[string]string          `json:"labels"`
+	Extra   map[string]any             `json:"extra"`
+	Value   any                        `json:"value"`
+	Nested  map[string]*pointerAddress `json:"nested"`
+}
+
+func newPointerPerson() pointerPerson {
+	return pointerPerson{
+		Name:    "Ann",
+		Age:     30,
+		Address: pointerAddress{Street: "Main", City: "Oslo"},
+		Tags:    []string{"a", "b", "c"},
+		Scores:  [3]int{1, 2, 3},
+		ByID:    map[int]pointerAddress{7: {City: "Bergen"}},
+		Labels:  map[string]string{"app/name": "web", "a~b": "x"},
+		Extra:   map[string]any{"list": []any{1.0, map[string]any{"k": "v"}}},
+		Value:   map[string]any{"n": 1},
+	}
+}
+
+func TestGet(t *testing.T) {
+	person := newPointerPerson()
+
+	t.Run("field", func(t *testing.T) {
+		got, err := Get(person, "/name")
+		require.NoError(t, err)
+		assert.Equal(t, "Ann", got)
+	})
+
+	t.Run("nested field", func(t *testing.T) {
+		got, err := Get(person, "/address/city")
+		require.NoError(t, err)
+		assert.Equal(t, "Oslo", got)
+	})
+
+	t.Run("struct value", func(t *testing.T) {
+		got, err := Get(person, "/address")
+		require.NoError(t, err)
+		assert.Equal(t, pointerAddress{Street: "Main", City: "Oslo"}, got)
+	})
+
+	t.Run("slice element", func(t *testing.T) {
+		got, err := Get(person, "/tags/1")
+		require.NoError(t, err)
+		assert.Equal(t, "b", got)
+	})
+
+	t.Run("array element", func(t *testing.T) {
+		got, err := Get(person, "/scores/2")
+		require.NoError(t, err)
+		assert.Equal(t, 3, got)
+	})
+
+	t.Run("typed map key", func(t *testing.T) {
+		got, err := Get(person, "/by_id/7/city")
+		require.NoError(t, err)
+		assert.Equal(t, "Bergen", got)
+	})
+
+	t.Run("escaped slash", func(t *testing.T) {
+		got, err := Get(person, "/labels/app~1name")
+		require.NoError(t, err)
+		assert.Equal(t, "web", got)
+	})
+
+	t.Run("escaped tilde", func(t *testing.T) {
+		got, err := Get(person, "/labels/a~0b")
+		require.NoError(t, err)
+		assert.Equal(t, "x", got)
+	})
+
+	t.Run("within interfaces", func(t *testing.T) {
+		got, err := Get(person, "/extra/list/1/k")
+		require.NoError(t, err)
+		assert.Equal(t, "v", got)
+	})
+
+	t.Run("interface field", func(t *testing.T) {
+		got, err := Get(person, "/value/n")
+		require.NoError(t, err)
+		assert.Equal(t, 1, got)
+	})
+
+	t.Run("nil pointer", func(t *testing.T) {
+		got, err := Get(person, "/work")
+		require.NoError(t, err)
+		assert.Equal(t, (*pointerAddress)(nil), got)
+	})
+
+	t.Run("through a pointer", func(t *testing.T) {
+		got, err := Get(&person, "/address/city")
+		require.NoError(t, err)
+		assert.Equal(t, "Oslo", got)
+	})
+
+	t.Run("whole value", func(t *testing.T) {
+		got, err := Get(person, "")
+		require.NoError(t, err)
+		assert.Equal(t, person, got)
+	})
+}
+
+func TestGet_Errors(t *testing.T) {
+	person := newPointerPerson()
+
+	t.Run("no leading slash", func(t *testing.T) {
+		_, err := Get(person, "name")
+		assert.ErrorContains(t, err, "must be empty or start with /")
+	})
+
+	t.Run("bad escape", func(t *testing.T) {
+		_, err := Get(person, "/labels/a~2b")
+		assert.ErrorContains(t, err, "bad escape")
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		_, err := Get(person, "/missing")
+		assert.ErrorContains(t, err, `field "missing" not found`)
+	})
+
+	t.Run("index out of range", func(t *testing.T) {
+		_, err := Get(person, "/tags/3")
+		assert.ErrorContains(t, err, "out of range")
+	})
+
+	t.Run("leading zero", func(t *testing.T) {
+		_, err := Get(person, "/tags/01")
+		assert.ErrorContains(t, err, "invalid index")
+	})
+
+	t.Run("append token", func(t *testing.T) {
+		_, err := Get(person, "/tags/-")
+		assert.ErrorContains(t, err, "invalid index")
+	})
+
+	t.Run("bad map key", func(t *testing.T) {
+		_, err := Get(person, "/by_id/x")
+		assert.ErrorContains(t, err, "cannot convert map key")
+	})
+
+	t.Run("missing map key", func(t *testing.T) {
+		_, err := Get(person, "/by_id/8")
+		assert.ErrorContains(t, err, `key "8" not found`)
+	})
+
+	t.Run("below nil pointer", func(t *testing.T) {
+		_, err := Get(person, "/work/city")
+		assert.ErrorContains(t, err, "parent is nil")
+	})
+
+	t.Run("below a scalar", func(t *testing.T) {
+		_, err := Get(person, "/name/first")
+		assert.ErrorContains(t, err, "cannot index string")
+	})
+}
+
+func TestSet(t *testing.T) {
+	person := newPointerPerson()
+
+	require.NoError(t, Set(&person, "/name", "Bob"))
+	require.NoError(t, Set(&person, "/age", 41.0))
+	require.NoError(t, Set(&person, "/address/city", "Boston"))
+	require.NoError(t, Set(&person, "/work/city", "Cambridge"))
+	require.NoError(t, Set(&person, "/tags/0", "z"))
+	require.NoError(t, Set(&person, "/tags/-", "d"))
+	require.NoError(t, Set(&person, "/scores/1", "20"))
+	require.NoError(t, Set(&person, "/by_id/7/street", "Harbor"))
+	require.NoError(t, Set(&person, "/by_id/8", map[string]any{"city": "Trondheim"}))
+	require.NoError(t, Set(&person, "/labels/app~1name", "api"))
+	require.NoError(t, Set(&person, "/extra/list/1/k", "w"))
+	require.NoError(t, Set(&person, "/value/n", 2))
+	require.NoError(t, Set(&person, "/nested/a", map[string]any{"city": "Tromsø"}))
+
+	want := newPointerPerson()
+	want.Name = "Bob"
+	want.Age = 41
+	want.Address.City = "Boston"
+	want.Work = &pointerAddress{City: "Cambridge"}
+	want.Tags = []string{"z", "b", "c", "d"}
+	want.Scores[1] = 20
+	want.ByID = map[int]pointerAddress{7: {Street: "Harbor", City: "Bergen"}, 8: {City: "Trondheim"}}
+	want.Labels["app/name"] = "api"
+	want.Extra = map[string]any{"list": []any{1.0, map[string]any{"k": "w"}}}
+	want.Value = map[string]any{"n": 2}
+	want.Nested = map[string]*pointerAddress{"a": {City: "Tromsø"}}
+	assert.Equal(t, want, person)
+}
+
+func TestSet_ReplacesValues(t *testing.T) {
+	person := newPointerPerson()
+
+	// A map replaces a struct value instead of patching it
+	require.NoError(t, Set(&person, "/address", map[string]any{"city": "Boston"}))
+	assert.Equal(t, pointerAddress{City: "Boston"}, person.Address)
+
+	require.NoError(t, Set(&person, "/tags", nil))
+	assert.Nil(t, person.Tags)
+
+	require.NoError(t, Set(&person, "", map[string]any{"name": "New"}))
+	assert.Equal(t, pointerPerson{Name: "New"}, person)
+
+	labels := map[string]string{}
+	require.NoError(t, Set(&labels, "/x", "y"))
+	assert.Equal(t, map[string]string{"x": "y"}, labels)
+}
+
+func TestSet_Errors(t *testing.T) {
+	person := newPointerPerson()
+
+	t.Run("nil for scalar", func(t *testing.T) {
+		assert.ErrorContains(t, Set(&person, "/age", nil), "cannot set non-nillable field")
+	})
+
+	t.Run("unconvertible value", func(t *testing.T) {
+		assert.ErrorContains(t, Set(&person, "/age", []any{}), "cannot convert")
+	})
+
+	t.Run("unknown parent", func(t *testing.T) {
+		assert.ErrorContains(t, Set(&person, "/missing/x", 1), `field "missing" not found`)
+	})
+
+	t.Run("missing map key parent", func(t *testing.T) {
+		assert.ErrorContains(t, Set(&person, "/by_id/9/city", "x"), `key "9" not found`)
+	})
+
+	t.Run("append to array", func(t *testing.T) {
+		assert.ErrorContains(t, Set(&person, "/scores/-", 1), "invalid index")
+	})
+
+	t.Run("index out of range", func(t *testing.T) {
+		assert.ErrorContains(t, Set(&person, "/tags/5", "x"), "out of range")
+	})
+
+	t.Run("not a pointer", func(t *testing.T) {
+		assert.ErrorContains(t, Set(person, "/name", "x"), "non-nil pointer")
+	})
+
+	assert.Equal(t, newPointerPerson(), person)
+}
+
+func TestDelete(t *testing.T) {
+	person := newPointerPerson()
+	tags := person.Tags
+
+	require.NoError(t, Delete(&person, "/name"))
+	require.NoError(t, Delete(&person, "/tags/1"))
+	require.NoError(t, Delete(&person, "/labels/a~0b"))
+	require.NoError(t, Delete(&person, "/by_id/7/city"))
+	require.NoError(t, Delete(&person, "/extra/list/0"))
+	require.NoError(t, Delete(&person, "/value/n"))
+
+	want := newPointerPerson()
+	want.Name = ""
+	want.Tags = []string{"a", "c"}
+	want.Labels = map[string]string{"app/name": "web"}
+	want.ByID = map[int]pointerAddress{7: {}}
+	want.Extra = map[string]any{"list": []any{map[string]any{"k": "v"}}}
+	want.Value = map[string]any{}
+	assert.Equal(t, want, person)
+
+	// The original backing array is left alone
+	assert.Equal(t, []string{"a", "b", "c"}, tags)
+}
+
+func TestDelete_Errors(t *testing.T) {
+	person := newPointerPerson()
+
+	t.Run("whole value", func(t *testing.T) {
+		assert.ErrorContains(t, Delete(&person, ""), "cannot delete the whole value")
+	})
+
+	t.Run("array element", func(t *testing.T) {
+		assert.ErrorContains(t, Delete(&person, "/scores/0"), "cannot delete an element of array")
+	})
+
+	t.Run("missing map key", func(t *testing.T) {
+		assert.ErrorContains(t, Delete(&person, "/labels/missing"), `key "missing" not found`)
+	})
+
+	t.Run("below nil pointer", func(t *testing.T) {
+		assert.ErrorContains(t, Delete(&person, "/work/city"), "parent is nil")
+	})
+
+	t.Run("index out of range", func(t *testing.T) {
+		assert.ErrorContains(t, Delete(&person, "/tags/3"), "out of range")
+	})
+}
+
+func TestParsePointer(t *testing.T) {
+	tokens, err := parsePointer("/a~1b/~01/")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a/b", "~1", ""}, tokens)
+
+	tokens, err = parsePointer("")
+	require.NoError(t, err)
+	assert.Empty(t, tokens)
+}
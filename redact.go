@@ -0,0 +1,208 @@
+package structdiff
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Redacted is emitted by ToMap and the diff functions in place of a redacted
+// value: one of a struct field tagged `diff:"redact"` or at a path given to
+// Redact. Changes to redacted values are still detected, but only the
+// placeholder appears in the patch.
+//
+// It is RedactedPlaceholder, or with RedactWithHash "hmac-sha256:" followed by
+// the hex HMAC-SHA256 of the JSON form of the value, keyed with the salt.
+// Since it is a string type, it marshals to JSON as a plain string.
+//
+// Redacted values are markers only: ApplyToStruct refuses to apply them. After
+// a round trip through JSON they are plain strings, which apply as any other
+// string does, since "[REDACTED]" may well be a legitimate value.
+type Redacted string
+
+// RedactedPlaceholder is the Redacted value used unless RedactWithHash is given.
+const RedactedPlaceholder Redacted = "[REDACTED]"
+
+// isRedactedField reports whether field is tagged `diff:"redact"`.
+func isRedactedField(field reflect.StructField) bool {
+	tag := field.Tag.Get("diff")
+	for tag != "" {
+		var opt string
+		opt, tag, _ = strings.Cut(tag, ",")
+		if opt == "redact" {
+			return true
+		}
+	}
+	return false
+}
+
+// redacted reports whether the value at path is redacted by Redact.
+func (o *options) redacted(path []string) bool {
+	for _, r := range o.redactPaths {
+		if len(r) <= len(path) && slices.Equal(r, path[:len(r)]) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactedBelow reports whether a value below path is redacted by Redact.
+func (o *options) redactedBelow(path []string) bool {
+	for _, r := range o.redactPaths {
+		if len(r) > len(path) && slices.Equal(r[:len(path)], path) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactValue returns what to emit in place of the redacted value v.
+func (o *options) redactValue(v reflect.Value) (any, error) {
+	if o.redactSalt == nil {
+		return RedactedPlaceholder, nil
+	}
+
+	// Hash the value itself, not its redacted form
+	paths := o.redactPaths
+	o.redactPaths = nil
+	val, err := toMapValue(v, o)
+	o.redactPaths = paths
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := json.Marshal(val)
+	if err != nil {
+		return nil, err
+	}
+	mac := hmac.New(sha256.New, o.redactSalt)
+	mac.Write(data)
+	return Redacted("hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))), nil
+}
+
+// isRedactedPatchValue reports whether a patch value is a redaction
+// placeholder.
+func isRedactedPatchValue(v any) bool {
+	_, ok := v.(Redacted)
+	return ok
+}
+
+// redactedAt reports whether the value at the current path extended by key
+// is redacted by Redact.
+func (d *differ) redactedAt(key string) bool {
+	if len(d.o.redactPaths) == 0 {
+		return false
+	}
+	d.push(key)
+	defer d.pop()
+	return d.o.redacted(d.path)
+}
+
+// diffRedacted reports a change at the current path extended by key between
+// two values that are redacted, with placeholders in place of the values.
+// Absent values are invalid or nil, and equal tells whether the values are
+// equal when both are present.
+func (d *differ) diffRedacted(key string, oldVal, newVal reflect.Value, equal bool, typ reflect.Type) error {
+	oldAbsent := isNilValue(oldVal)
+	newAbsent := isNilValue(newVal)
+	if (oldAbsent && newAbsent) || (!oldAbsent && !newAbsent && equal) {
+		return nil
+	}
+
+	var old, new any
+	var err error
+	if !oldAbsent && d.wantOld {
+		if old, err = d.o.redactValue(oldVal); err != nil {
+			return err
+		}
+	}
+	if !newAbsent && !d.noValues {
+		if new, err = d.o.redactValue(newVal); err != nil {
+			return err
+		}
+	}
+	d.emit(key, !oldAbsent, !newAbsent, old, new, typ)
+	return nil
+}
+
+// pushPath extends the path of the value ToMap converts with key, counting
+// its depth. The path itself is only tracked when redacting by path.
+func (o *options) pushPath(key string) {
+	o.depth++
+	if len(o.redactPaths) > 0 {
+		o.path = append(o.path, key)
+	}
+}
+
+func (o *options) popPath() {
+	o.depth--
+	if len(o.redactPaths) > 0 {
+		o.path = o.path[:len(o.path)-1]
+	}
+}
+
+// syncPath makes the path ToMap tracks start at the current path, before
+// values found by the differ are converted.
+func (d *differ) syncPath() {
+	if len(d.o.redactPaths) > 0 {
+		d.o.path = append(d.o.path[:0], d.path...)
+	}
+}
+
+// rawValue returns the value to emit for a value taken as is from a
+// map[string]any at the current path. Values that may hold redacted values,
+// including any that hold interfaces, are converted with ToMap, so that these
+// are replaced; others are emitted unchanged.
+func (d *differ) rawValue(v any) (any, error) {
+	if v == nil || (len(d.o.redactPaths) == 0 && !hasRedactedFields(reflect.TypeOf(v))) {
+		return v, nil
+	}
+	d.syncPath()
+	return toMapValue(reflect.ValueOf(v), d.o)
+}
+
+// redactedTypes caches hasRedactedFields.
+var redactedTypes sync.Map // map[reflect.Type]bool
+
+// hasRedactedFields reports whether values of type t may contain struct
+// fields tagged `diff:"redact"`, following pointers and the elements of
+// slices, arrays and maps. Since an interface may hold a value of any type,
+// it may always contain them.
+func hasRedactedFields(t reflect.Type) bool {
+	if has, ok := redactedTypes.Load(t); ok {
+		return has.(bool)
+	}
+	has := typeHasRedactedFields(t, make(map[reflect.Type]bool))
+	redactedTypes.Store(t, has)
+	return has
+}
+
+func typeHasRedactedFields(t reflect.Type, seen map[reflect.Type]bool) bool {
+	if seen[t] {
+		return false
+	}
+	seen[t] = true
+
+	switch t.Kind() {
+	case reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Slice, reflect.Array, reflect.Map:
+		return typeHasRedactedFields(t.Elem(), seen)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if !field.IsExported() {
+				continue
+			}
+			if isRedactedField(field) || typeHasRedactedFields(field.Type, seen) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,253 @@
+package structdiff
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type redactCredentials struct {
+	Token  string `json:"token" diff:"redact"`
+	Scopes []string
+}
+
+type redactUser struct {
+	Name     string            `json:"name"`
+	Password string            `json:"password" diff:"redact"`
+	Secret   *string           `json:"secret,omitempty" diff:"redact"`
+	Creds    redactCredentials `json:"creds"`
+	Settings map[string]string `json:"settings"`
+}
+
+func TestRedact_ToMap(t *testing.T) {
+	user := redactUser{
+		Name:     "alice",
+		Password: "hunter2",
+		Creds:    redactCredentials{Token: "abc", Scopes: []string{"read"}},
+		Settings: map[string]string{"theme": "dark", "api_key": "k"},
+	}
+
+	t.Run("tagged fields", func(t *testing.T) {
+		m := ToMap(user)
+		assert.Equal(t, RedactedPlaceholder, m["password"])
+		assert.NotContains(t, m, "secret") // nil pointers are still omitted
+		assert.Equal(t, RedactedPlaceholder, m["creds"].(map[string]any)["token"])
+		assert.Equal(t, []any{"read"}, m["creds"].(map[string]any)["Scopes"])
+	})
+
+	t.Run("paths", func(t *testing.T) {
+		m := ToMap(user, Redact("settings.api_key", "creds.Scopes"))
+		assert.Equal(t, map[string]any{"theme": "dark", "api_key": RedactedPlaceholder}, m["settings"])
+		assert.Equal(t, RedactedPlaceholder, m["creds"].(map[string]any)["Scopes"])
+		assert.Equal(t, "alice", m["name"])
+	})
+
+	t.Run("salted hash", func(t *testing.T) {
+		m1 := ToMap(user, RedactWithHash([]byte("salt")))
+		m2 := ToMap(user, RedactWithHash([]byte("salt")))
+		m3 := ToMap(user, RedactWithHash([]byte("pepper")))
+
+		hash := m1["password"].(Redacted)
+		assert.True(t, strings.HasPrefix(string(hash), "hmac-sha256:"))
+		assert.Equal(t, hash, m2["password"])
+		assert.NotEqual(t, hash, m3["password"])
+		assert.NotContains(t, string(hash), "hunter2")
+	})
+}
+
+func TestRedact_DiffStructs(t *testing.T) {
+	old := redactUser{Name: "alice", Password: "hunter2", Creds: redactCredentials{Token: "abc"}}
+	new := redactUser{Name: "alice", Password: "correct horse", Secret: stringPtr("s"), Creds: redactCredentials{Token: "abc"}}
+
+	t.Run("changes are detected but not revealed", func(t *testing.T) {
+		diff, err := DiffStructs(old, new)
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"password": RedactedPlaceholder,
+			"secret":   RedactedPlaceholder,
+		}, diff)
+	})
+
+	t.Run("unchanged redacted fields are omitted", func(t *testing.T) {
+		diff, err := DiffStructs(old, old)
+		require.NoError(t, err)
+		assert.Empty(t, diff)
+	})
+
+	t.Run("removal", func(t *testing.T) {
+		diff, err := DiffStructs(new, old)
+		require.NoError(t, err)
+		assert.Contains(t, diff, "secret")
+		assert.Nil(t, diff["secret"])
+	})
+
+	t.Run("paths redact a subtree", func(t *testing.T) {
+		changed := old
+		changed.Creds = redactCredentials{Token: "xyz", Scopes: []string{"admin"}}
+		diff, err := DiffStructs(old, changed, Redact("creds"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"creds": RedactedPlaceholder}, diff)
+	})
+
+	t.Run("hashes differ between values", func(t *testing.T) {
+		opt := RedactWithHash([]byte("salt"))
+		d1, err := DiffStructs(old, new, opt)
+		require.NoError(t, err)
+		other := new
+		other.Password = "tr0ub4dor"
+		d2, err := DiffStructs(old, other, opt)
+		require.NoError(t, err)
+		assert.NotEqual(t, d1["password"], d2["password"])
+	})
+
+	t.Run("changes", func(t *testing.T) {
+		changes, err := Changes(old, new)
+		require.NoError(t, err)
+		require.Len(t, changes, 2)
+		assert.Equal(t, Change{Path: []string{"password"}, Kind: Modified, Old: RedactedPlaceholder, New: RedactedPlaceholder, Type: changes[0].Type}, changes[0])
+	})
+
+	t.Run("nested in maps", func(t *testing.T) {
+		oldMap := map[string]any{"users": map[string]any{}}
+		newMap := map[string]any{"users": map[string]any{"alice": new}}
+		diff, err := DiffMaps(oldMap, newMap)
+		require.NoError(t, err)
+
+		data, err := json.Marshal(diff)
+		require.NoError(t, err)
+		assert.NotContains(t, string(data), "correct horse")
+		assert.Contains(t, string(data), string(RedactedPlaceholder))
+	})
+}
+
+func TestRedact_DiffMaps(t *testing.T) {
+	old := map[string]any{
+		"user":   "alice",
+		"config": map[string]any{"token": "abc", "region": "eu"},
+	}
+	new := map[string]any{
+		"user":   "alice",
+		"config": map[string]any{"token": "xyz", "region": "us"},
+	}
+
+	diff, err := DiffMaps(old, new, Redact("config.token"))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]any{
+		"config": map[string]any{"token": RedactedPlaceholder, "region": "us"},
+	}, diff)
+
+	t.Run("added subtree", func(t *testing.T) {
+		diff, err := DiffMaps(map[string]any{}, new, Redact("config.token"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"user":   "alice",
+			"config": map[string]any{"token": RedactedPlaceholder, "region": "us"},
+		}, diff)
+	})
+
+	t.Run("escaped dots in keys", func(t *testing.T) {
+		old := map[string]any{"labels": map[string]any{"app.io/key": "a", "app": map[string]any{"io/key": "a"}}}
+		new := map[string]any{"labels": map[string]any{"app.io/key": "b", "app": map[string]any{"io/key": "b"}}}
+
+		diff, err := DiffMaps(old, new, Redact(`labels.app\.io/key`))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"labels": map[string]any{
+			"app.io/key": RedactedPlaceholder,
+			"app":        map[string]any{"io/key": "b"},
+		}}, diff)
+	})
+}
+
+func TestRedact_Interfaces(t *testing.T) {
+	type holder struct {
+		Items []any `json:"items"`
+		Value any   `json:"value"`
+	}
+	user := redactUser{Name: "alice", Password: "hunter2"}
+	redacted := map[string]any{
+		"name":     "alice",
+		"password": RedactedPlaceholder,
+		"creds":    map[string]any{"token": RedactedPlaceholder},
+	}
+
+	t.Run("in []any", func(t *testing.T) {
+		diff, err := DiffMaps(map[string]any{}, map[string]any{"users": []any{user}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"users": []any{redacted}}, diff)
+	})
+
+	t.Run("in map[string]any", func(t *testing.T) {
+		diff, err := DiffMaps(map[string]any{}, map[string]any{"users": map[string]any{"x": user}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"users": map[string]any{"x": redacted}}, diff)
+	})
+
+	t.Run("in any fields", func(t *testing.T) {
+		m := ToMap(holder{Value: user})
+		assert.Equal(t, redacted, m["value"])
+
+		diff, err := Diff(holder{}, holder{Items: []any{user}})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"items": []any{redacted}}, diff)
+
+		diff, err = Diff(holder{Value: 1}, holder{Value: user})
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{"value": redacted}, diff)
+
+		changes, err := Changes(holder{}, holder{Items: []any{user}})
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, []any{redacted}, changes[0].New)
+	})
+
+	t.Run("paths", func(t *testing.T) {
+		old := map[string]any{"users": []any{map[string]any{"name": "alice", "pin": "1234"}}}
+		new := map[string]any{"users": []any{map[string]any{"name": "alice", "pin": "4321"}}, "extra": []any{}}
+
+		diff, err := DiffMaps(map[string]any{}, new, Redact("users.0.pin"))
+		require.NoError(t, err)
+		assert.Equal(t, map[string]any{
+			"users": []any{map[string]any{"name": "alice", "pin": RedactedPlaceholder}},
+			"extra": []any{},
+		}, diff)
+
+		m := ToMap(holder{Value: old}, Redact("value.users.0.pin"))
+		assert.Equal(t, map[string]any{
+			"users": []any{map[string]any{"name": "alice", "pin": RedactedPlaceholder}},
+		}, m["value"])
+	})
+
+	data, err := json.Marshal(ToMap(holder{Items: []any{user}, Value: map[string]any{"u": user}}))
+	require.NoError(t, err)
+	assert.NotContains(t, string(data), "hunter2")
+}
+
+func TestRedact_ApplyRefusesPlaceholders(t *testing.T) {
+	old := redactUser{Name: "alice", Password: "hunter2"}
+	new := redactUser{Name: "bob", Password: "correct horse"}
+
+	diff, err := DiffStructs(old, new)
+	require.NoError(t, err)
+
+	target := old
+	err = ApplyToStruct(&target, diff)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "redacted")
+	assert.Equal(t, "hunter2", target.Password)
+
+	// A plain string equal to the placeholder is an ordinary value
+	require.NoError(t, ApplyToStruct(&target, map[string]any{"password": "[REDACTED]"}))
+	assert.Equal(t, "[REDACTED]", target.Password)
+}
+
+func TestRedact_Equal(t *testing.T) {
+	old := redactUser{Name: "alice", Password: "hunter2"}
+	new := old
+	new.Password = "correct horse"
+
+	assert.True(t, Equal(old, old))
+	assert.False(t, Equal(old, new))
+}
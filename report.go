@@ -0,0 +1,205 @@
+package structdiff
+
+import (
+	"fmt"
+	"html/template"
+	"reflect"
+	"strings"
+)
+
+// reportRow is one row of an HTML or Markdown report: either a change, or a
+// collapsed run of unchanged siblings.
+type reportRow struct {
+	Path      string
+	Kind      string // "added", "removed", "modified" or "unchanged"
+	Old       string
+	New       string
+	Unchanged int
+}
+
+// HTMLReport renders the differences between old and new as an HTML table
+// with one row per change and side-by-side old and new values, using the same
+// traversal and rules as Changes. Values are escaped with html/template.
+//
+// Unchanged fields and keys are not listed: each struct or map containing
+// changes ends with a single row counting its unchanged entries. Redacted
+// values are shown as their placeholders.
+//
+// Rows have the class "added", "removed", "modified" or "unchanged" for
+// styling. Returns an error if an error occurs during diffing.
+func HTMLReport(old, new any, opts ...Option) (string, error) {
+	rows, err := buildReport(old, new, newOptions(opts))
+	if err != nil {
+		return "", err
+	}
+
+	var sb strings.Builder
+	if err := htmlReportTemplate.Execute(&sb, rows); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+var htmlReportTemplate = template.Must(template.New("report").Parse(
+	`{{if not .}}<p class="structdiff">No differences.</p>
+{{else}}<table class="structdiff">
+<thead><tr><th>Path</th><th>Old</th><th>New</th></tr></thead>
+<tbody>
+{{range .}}{{if .Unchanged}}<tr class="unchanged"><td>{{.Path}}</td><td colspan="2">{{.Unchanged}} unchanged</td></tr>
+{{else}}<tr class="{{.Kind}}"><td>{{.Path}}</td><td>{{.Old}}</td><td>{{.New}}</td></tr>
+{{end}}{{end}}</tbody>
+</table>
+{{end}}`))
+
+// MarkdownReport renders the differences between old and new as a Markdown
+// table, for GitHub comments and the like. It has the same rows as
+// HTMLReport; values are escaped so that they cannot break the table or
+// inject markup.
+func MarkdownReport(old, new any, opts ...Option) (string, error) {
+	rows, err := buildReport(old, new, newOptions(opts))
+	if err != nil {
+		return "", err
+	}
+	if len(rows) == 0 {
+		return "No differences.\n", nil
+	}
+
+	var sb strings.Builder
+	sb.WriteString("| Path | Old | New |\n| --- | --- | --- |\n")
+	for _, row := range rows {
+		if row.Unchanged > 0 {
+			fmt.Fprintf(&sb, "| %s | _%d unchanged_ | |\n", markdownEscape(row.Path), row.Unchanged)
+			continue
+		}
+		fmt.Fprintf(&sb, "| %s | %s | %s |\n", markdownEscape(row.Path), markdownEscape(row.Old), markdownEscape(row.New))
+	}
+	return sb.String(), nil
+}
+
+// markdownEscape escapes s for use in a Markdown table cell: HTML special
+// characters become entities, Markdown punctuation is backslash-escaped and
+// newlines become <br>.
+func markdownEscape(s string) string {
+	var sb strings.Builder
+	for _, r := range s {
+		switch r {
+		case '&':
+			sb.WriteString("&amp;")
+		case '<':
+			sb.WriteString("&lt;")
+		case '>':
+			sb.WriteString("&gt;")
+		case '\n':
+			sb.WriteString("<br>")
+		case '\r':
+		case '\\', '`', '*', '_', '[', ']', '|', '#', '~', '!':
+			sb.WriteByte('\\')
+			sb.WriteRune(r)
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
+
+// reportNode is a node of the tree of changed paths, with children in the
+// order the changes were found.
+type reportNode struct {
+	key      string
+	change   *Change
+	children []*reportNode
+	index    map[string]*reportNode
+}
+
+func (n *reportNode) child(key string) *reportNode {
+	if c, ok := n.index[key]; ok {
+		return c
+	}
+	c := &reportNode{key: key}
+	if n.index == nil {
+		n.index = make(map[string]*reportNode)
+	}
+	n.index[key] = c
+	n.children = append(n.children, c)
+	return c
+}
+
+// buildReport computes the rows of a report on the differences between old
+// and new.
+func buildReport(old, new any, o *options) ([]reportRow, error) {
+	o.sortKeys = true
+	c := &changeCollector{}
+	if err := newDiffer(o, c, true).diffAny(old, new); err != nil {
+		return nil, err
+	}
+
+	root := &reportNode{}
+	for i := range c.changes {
+		n := root
+		for _, key := range c.changes[i].Path {
+			n = n.child(key)
+		}
+		n.change = &c.changes[i]
+	}
+
+	var rows []reportRow
+	root.rows(nil, old, new, o, &rows)
+	return rows, nil
+}
+
+// rows appends the rows for n, at path, whose old and new values are old
+// and new.
+func (n *reportNode) rows(path []string, old, new any, o *options, rows *[]reportRow) {
+	if n.change != nil {
+		row := reportRow{Path: formatPath(path), Kind: n.change.Kind.String()}
+		if n.change.Old != nil {
+			row.Old = formatValue(n.change.Old)
+		}
+		if n.change.New != nil {
+			row.New = formatValue(n.change.New)
+		}
+		*rows = append(*rows, row)
+		return
+	}
+
+	if len(n.children) == 0 {
+		return
+	}
+
+	oldMap := reportMap(old, o)
+	newMap := reportMap(new, o)
+	for _, child := range n.children {
+		child.rows(append(path[:len(path):len(path)], child.key), oldMap[child.key], newMap[child.key], o, rows)
+	}
+
+	// Collapse the unchanged entries into a single row
+	unchanged := 0
+	for key := range newMap {
+		if _, changed := n.index[key]; !changed {
+			unchanged++
+		}
+	}
+	for key := range oldMap {
+		_, changed := n.index[key]
+		if _, inNew := newMap[key]; !changed && !inNew {
+			unchanged++
+		}
+	}
+	if unchanged > 0 {
+		*rows = append(*rows, reportRow{Path: formatPath(path), Kind: "unchanged", Unchanged: unchanged})
+	}
+}
+
+// reportMap returns the entries of a struct or map value, keyed as in a
+// patch, or nil if v is neither.
+func reportMap(v any, o *options) map[string]any {
+	if m, ok := v.(map[string]any); ok {
+		return m
+	}
+	val, err := toMapValue(reflect.ValueOf(v), o)
+	if err != nil {
+		return nil
+	}
+	m, _ := val.(map[string]any)
+	return m
+}
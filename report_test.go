@@ -0,0 +1,135 @@
+package structdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type reportAccount struct {
+	Name     string            `json:"name"`
+	Email    string            `json:"email"`
+	Password string            `json:"password"`
+	Address  formatAddress     `json:"address"`
+	Labels   map[string]string `json:"labels"`
+}
+
+func reportPair() (reportAccount, reportAccount) {
+	old := reportAccount{
+		Name:     "Alice",
+		Email:    "alice@example.com",
+		Password: "hunter2",
+		Address:  formatAddress{Street: "1 Main St", City: "NYC"},
+		Labels:   map[string]string{"team": "x", "env": "prod"},
+	}
+	new := old
+	new.Name = "Alicia"
+	new.Password = "correct horse"
+	new.Address.City = "Boston"
+	new.Labels = map[string]string{"team": "x", "env": "prod", "note": "<b>hi</b> | *there*"}
+	return old, new
+}
+
+func TestMarkdownReport(t *testing.T) {
+	old, new := reportPair()
+
+	out, err := MarkdownReport(old, new, Redact("password"))
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	assert.Equal(t, []string{
+		"| Path | Old | New |",
+		"| --- | --- | --- |",
+		`| name | "Alice" | "Alicia" |`,
+		`| password | \[REDACTED\] | \[REDACTED\] |`,
+		`| address.city | "NYC" | "Boston" |`,
+		`| address | _1 unchanged_ | |`,
+		`| labels.note |  | "&lt;b&gt;hi&lt;/b&gt; \| \*there\*" |`,
+		`| labels | _2 unchanged_ | |`,
+		`| (root) | _1 unchanged_ | |`,
+	}, lines)
+	assert.NotContains(t, out, "hunter2")
+}
+
+func TestHTMLReport(t *testing.T) {
+	old, new := reportPair()
+
+	out, err := HTMLReport(old, new, Redact("password"))
+	require.NoError(t, err)
+
+	assert.Contains(t, out, `<table class="structdiff">`)
+	assert.Contains(t, out, `<tr class="modified"><td>name</td><td>&#34;Alice&#34;</td><td>&#34;Alicia&#34;</td></tr>`)
+	assert.Contains(t, out, `<tr class="modified"><td>password</td><td>[REDACTED]</td><td>[REDACTED]</td></tr>`)
+	assert.Contains(t, out, `<tr class="unchanged"><td>address</td><td colspan="2">1 unchanged</td></tr>`)
+	assert.Contains(t, out, `<tr class="added"><td>labels.note</td><td></td>`)
+	assert.NotContains(t, out, "<b>hi</b>")
+	assert.NotContains(t, out, "hunter2")
+	assert.NotContains(t, out, "correct horse")
+}
+
+func TestReport_EdgeCases(t *testing.T) {
+	t.Run("no differences", func(t *testing.T) {
+		old, _ := reportPair()
+
+		out, err := MarkdownReport(old, old)
+		require.NoError(t, err)
+		assert.Equal(t, "No differences.\n", out)
+
+		out, err = HTMLReport(old, old)
+		require.NoError(t, err)
+		assert.Equal(t, "<p class=\"structdiff\">No differences.</p>\n", out)
+	})
+
+	t.Run("redacted subtree", func(t *testing.T) {
+		old, new := reportPair()
+
+		out, err := MarkdownReport(old, new, Redact("address"))
+		require.NoError(t, err)
+		assert.Contains(t, out, `| address | \[REDACTED\] | \[REDACTED\] |`)
+		assert.NotContains(t, out, "Boston")
+	})
+
+	t.Run("tagged fields", func(t *testing.T) {
+		type login struct {
+			User     string `json:"user"`
+			Password string `json:"password" diff:"redact"`
+		}
+		old := login{User: "alice", Password: "hunter2"}
+		new := login{User: "alicia", Password: "correct horse"}
+
+		out, err := MarkdownReport(old, new)
+		require.NoError(t, err)
+		assert.Contains(t, out, `| password | \[REDACTED\] | \[REDACTED\] |`)
+		assert.NotContains(t, out, "hunter2")
+		assert.NotContains(t, out, "correct horse")
+
+		out, err = HTMLReport(old, new)
+		require.NoError(t, err)
+		assert.Contains(t, out, `<tr class="modified"><td>password</td><td>[REDACTED]</td><td>[REDACTED]</td></tr>`)
+		assert.NotContains(t, out, "hunter2")
+		assert.NotContains(t, out, "correct horse")
+	})
+
+	t.Run("removed values", func(t *testing.T) {
+		out, err := MarkdownReport(map[string]any{"a": 1, "b": 2}, map[string]any{"b": 2})
+		require.NoError(t, err)
+		assert.Contains(t, out, "| a | 1 |  |")
+		assert.Contains(t, out, "| (root) | _1 unchanged_ | |")
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		_, err := HTMLReport(*newCycleTree("b"), *newCycleTree("c"))
+		assert.ErrorIs(t, err, ErrCycle)
+		_, err = MarkdownReport(*newCycleTree("b"), *newCycleTree("c"))
+		assert.ErrorIs(t, err, ErrCycle)
+	})
+}
+
+func TestMarkdownEscape(t *testing.T) {
+	assert.Equal(t, `a \| b`, markdownEscape("a | b"))
+	assert.Equal(t, `\*\_\[x\](y)`, markdownEscape("*_[x](y)"))
+	assert.Equal(t, "&lt;script&gt;<br>x", markdownEscape("<script>\nx"))
+	assert.Equal(t, "\\`code\\`", markdownEscape("`code`"))
+}
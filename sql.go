@@ -0,0 +1,197 @@
+package structdiff
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SQLDialect selects the placeholder syntax of the statements built by
+// SQLDialect.Update.
+type SQLDialect int
+
+const (
+	// SQLDollar numbers placeholders $1, $2, ..., as PostgreSQL does.
+	SQLDollar SQLDialect = iota
+
+	// SQLQuestion uses ? for every placeholder, as MySQL and SQLite do.
+	SQLQuestion
+
+	// SQLAtP numbers placeholders @p1, @p2, ..., as SQL Server does.
+	SQLAtP
+)
+
+// placeholder returns the placeholder for the nth argument, counting from 1.
+func (d SQLDialect) placeholder(n int) string {
+	switch d {
+	case SQLQuestion:
+		return "?"
+	case SQLAtP:
+		return "@p" + strconv.Itoa(n)
+	default:
+		return "$" + strconv.Itoa(n)
+	}
+}
+
+// SQLUpdate builds an UPDATE statement with PostgreSQL placeholders that
+// changes the row of old into new. See SQLDialect.Update.
+func SQLUpdate(table string, old, new any, keyFields ...string) (string, []any, error) {
+	return SQLDollar.Update(table, old, new, keyFields...)
+}
+
+// Update builds a parameterized UPDATE statement, with its arguments, that
+// changes the row of table holding old into new:
+//
+//	UPDATE users SET name = $1, nickname = NULL WHERE id = $2
+//
+// old and new are structs, or pointers to structs, of the same type, compared
+// field by field with Equal. Each changed top-level field is a column, named
+// by its "db" tag or else its JSON name; fields tagged `db:"-"` are left out,
+// as are fields tagged `json:"-"` without a "db" tag, so a column kept out of
+// JSON, such as a password hash, is still updated. Fields that became nil
+// are set to NULL. Structs, maps, slices and arrays are JSON
+// columns, set whole to their new value encoded as JSON, so a change deep
+// within them updates the column. Values implementing driver.Valuer,
+// time.Time and []byte are passed as they are.
+//
+// keyFields are the column names identifying the row; the WHERE clause
+// matches their values in old. Table and column names are inserted into the
+// statement as they are. Returns an empty statement if no column changed,
+// and an error if no key field is given or one is not a column.
+func (d SQLDialect) Update(table string, old, new any, keyFields ...string) (string, []any, error) {
+	if len(keyFields) == 0 {
+		return "", nil, fmt.Errorf("at least one key field is required")
+	}
+
+	oldVal, newVal := indirectValue(reflect.ValueOf(old)), indirectValue(reflect.ValueOf(new))
+	if oldVal.Kind() != reflect.Struct || newVal.Kind() != reflect.Struct || oldVal.Type() != newVal.Type() {
+		return "", nil, fmt.Errorf("old and new must be structs of the same type, got %T and %T", old, new)
+	}
+
+	var sets, wheres []string
+	var args []any
+
+	structType := newVal.Type()
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+		column, ok := sqlColumn(field)
+		if !ok {
+			continue
+		}
+		value := newVal.Field(i)
+		if Equal(oldVal.Field(i).Interface(), value.Interface()) {
+			continue
+		}
+
+		if isNilValue(value) {
+			sets = append(sets, column+" = NULL")
+			continue
+		}
+		arg, err := sqlArg(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("column %s: %w", column, err)
+		}
+		args = append(args, arg)
+		sets = append(sets, column+" = "+d.placeholder(len(args)))
+	}
+	if len(sets) == 0 {
+		return "", nil, nil
+	}
+
+	for _, key := range keyFields {
+		index := sqlColumnIndex(structType, key)
+		if index < 0 {
+			return "", nil, fmt.Errorf("key field %q is not a column of %s", key, structType)
+		}
+		value := oldVal.Field(index)
+		if isNilValue(value) {
+			wheres = append(wheres, key+" IS NULL")
+			continue
+		}
+		arg, err := sqlArg(value)
+		if err != nil {
+			return "", nil, fmt.Errorf("key field %s: %w", key, err)
+		}
+		args = append(args, arg)
+		wheres = append(wheres, key+" = "+d.placeholder(len(args)))
+	}
+
+	query := "UPDATE " + table + " SET " + strings.Join(sets, ", ") + " WHERE " + strings.Join(wheres, " AND ")
+	return query, args, nil
+}
+
+// sqlColumn returns the column name of a struct field, or false if the field
+// is not a column.
+func sqlColumn(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	jsonTag := field.Tag.Get("json")
+	dbTag := field.Tag.Get("db")
+	if dbTag == "-" || (jsonTag == "-" && dbTag == "") {
+		return "", false
+	}
+	return parseName(dbTag, parseName(jsonTag, field.Name)), true
+}
+
+// sqlColumnIndex returns the index of the field of structType with the given
+// column name, or -1.
+func sqlColumnIndex(structType reflect.Type, column string) int {
+	for i := 0; i < structType.NumField(); i++ {
+		if c, ok := sqlColumn(structType.Field(i)); ok && c == column {
+			return i
+		}
+	}
+	return -1
+}
+
+// sqlArg returns the statement argument for a non-nil column value: the value
+// itself for scalars, driver.Valuer implementations, time.Time and []byte,
+// and its JSON encoding for other structs, maps, slices and arrays.
+func sqlArg(v reflect.Value) (any, error) {
+	valuerType := reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	for {
+		if v.Type().Implements(valuerType) {
+			return v.Interface(), nil
+		}
+		if v.Kind() != reflect.Pointer && v.Kind() != reflect.Interface {
+			break
+		}
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.Type() == reflect.TypeOf(time.Time{}) {
+			return v.Interface(), nil
+		}
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), nil
+		}
+	case reflect.Map, reflect.Array:
+	default:
+		return v.Interface(), nil
+	}
+
+	data, err := json.Marshal(v.Interface())
+	if err != nil {
+		return nil, err
+	}
+	return string(data), nil
+}
+
+// indirectValue follows pointers until it reaches a non-pointer value or nil.
+func indirectValue(v reflect.Value) reflect.Value {
+	for v.Kind() == reflect.Pointer && !v.IsNil() {
+		v = v.Elem()
+	}
+	return v
+}
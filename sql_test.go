@@ -0,0 +1,176 @@
+package structdiff
+
+import (
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type sqlStatus string
+
+func (s sqlStatus) Value() (driver.Value, error) {
+	return "status:" + string(s), nil
+}
+
+type sqlAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type sqlUser struct {
+	ID        int               `json:"id" db:"id"`
+	TenantID  string            `json:"tenant" db:"tenant_id"`
+	Name      string            `json:"name" db:"full_name"`
+	Nickname  *string           `json:"nickname"`
+	Address   sqlAddress        `json:"address" db:"address"`
+	Tags      []string          `json:"tags" db:"tags"`
+	Labels    map[string]string `json:"labels" db:"labels"`
+	Avatar    []byte            `json:"avatar" db:"avatar"`
+	Status    sqlStatus         `json:"status" db:"status"`
+	UpdatedAt time.Time         `json:"updated_at" db:"updated_at"`
+	Cache     string            `json:"cache" db:"-"`
+}
+
+func newSQLUser() sqlUser {
+	nick := "annie"
+	return sqlUser{
+		ID:        7,
+		TenantID:  "t1",
+		Name:      "Ann",
+		Nickname:  &nick,
+		Address:   sqlAddress{Street: "Main", City: "Oslo"},
+		Tags:      []string{"a"},
+		Labels:    map[string]string{"team": "core"},
+		UpdatedAt: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+}
+
+func TestSQLUpdate(t *testing.T) {
+	old := newSQLUser()
+	new := newSQLUser()
+	nick := "ann"
+	new.Name = "Anne"
+	new.Nickname = &nick
+	new.Address.City = "Bergen"
+
+	query, args, err := SQLUpdate("users", old, &new, "id")
+	require.NoError(t, err)
+	assert.Equal(t, "UPDATE users SET full_name = $1, nickname = $2, address = $3 WHERE id = $4", query)
+	assert.Equal(t, []any{"Anne", "ann", `{"street":"Main","city":"Bergen"}`, 7}, args)
+}
+
+func TestSQLUpdate_Dialects(t *testing.T) {
+	old := newSQLUser()
+	new := newSQLUser()
+	new.Name = "Anne"
+	new.Tags = []string{"a", "b"}
+
+	t.Run("dollar", func(t *testing.T) {
+		query, args, err := SQLDollar.Update("users", old, new, "id", "tenant_id")
+		require.NoError(t, err)
+		assert.Equal(t, "UPDATE users SET full_name = $1, tags = $2 WHERE id = $3 AND tenant_id = $4", query)
+		assert.Equal(t, []any{"Anne", `["a","b"]`, 7, "t1"}, args)
+	})
+
+	t.Run("question", func(t *testing.T) {
+		query, args, err := SQLQuestion.Update("users", old, new, "id", "tenant_id")
+		require.NoError(t, err)
+		assert.Equal(t, "UPDATE users SET full_name = ?, tags = ? WHERE id = ? AND tenant_id = ?", query)
+		assert.Equal(t, []any{"Anne", `["a","b"]`, 7, "t1"}, args)
+	})
+
+	t.Run("at p", func(t *testing.T) {
+		query, args, err := SQLAtP.Update("users", old, new, "id", "tenant_id")
+		require.NoError(t, err)
+		assert.Equal(t, "UPDATE users SET full_name = @p1, tags = @p2 WHERE id = @p3 AND tenant_id = @p4", query)
+		assert.Equal(t, []any{"Anne", `["a","b"]`, 7, "t1"}, args)
+	})
+}
+
+func TestSQLUpdate_Null(t *testing.T) {
+	old := newSQLUser()
+	new := newSQLUser()
+	new.Nickname = nil
+	new.Labels = nil
+	new.Name = "Anne"
+
+	query, args, err := SQLUpdate("users", old, new, "id")
+	require.NoError(t, err)
+	assert.Equal(t, "UPDATE users SET full_name = $1, nickname = NULL, labels = NULL WHERE id = $2", query)
+	assert.Equal(t, []any{"Anne", 7}, args)
+}
+
+func TestSQLUpdate_HiddenFromJSON(t *testing.T) {
+	type account struct {
+		ID           int    `json:"id" db:"id"`
+		PasswordHash string `json:"-" db:"password_hash"`
+		Session      string `json:"-"`
+	}
+
+	query, args, err := SQLUpdate("accounts", account{ID: 1, PasswordHash: "a", Session: "x"}, account{ID: 1, PasswordHash: "b", Session: "y"}, "id")
+	require.NoError(t, err)
+	assert.Equal(t, "UPDATE accounts SET password_hash = $1 WHERE id = $2", query)
+	assert.Equal(t, []any{"b", 1}, args)
+
+	query, _, err = SQLUpdate("accounts", account{ID: 1, Session: "x"}, account{ID: 1, Session: "y"}, "id")
+	require.NoError(t, err)
+	assert.Empty(t, query)
+}
+
+func TestSQLUpdate_PassThroughValues(t *testing.T) {
+	old := newSQLUser()
+	new := newSQLUser()
+	new.Avatar = []byte{1, 2}
+	new.Status = "active"
+	new.UpdatedAt = old.UpdatedAt.Add(time.Hour)
+
+	query, args, err := SQLUpdate("users", old, new, "id")
+	require.NoError(t, err)
+	assert.Equal(t, "UPDATE users SET avatar = $1, status = $2, updated_at = $3 WHERE id = $4", query)
+	assert.Equal(t, []any{[]byte{1, 2}, sqlStatus("active"), new.UpdatedAt, 7}, args)
+}
+
+func TestSQLUpdate_NoChanges(t *testing.T) {
+	old := newSQLUser()
+	new := newSQLUser()
+	new.Cache = "ignored"
+
+	query, args, err := SQLUpdate("users", old, new, "id")
+	require.NoError(t, err)
+	assert.Empty(t, query)
+	assert.Nil(t, args)
+}
+
+func TestSQLUpdate_Errors(t *testing.T) {
+	type other struct {
+		ID int `db:"id"`
+	}
+
+	t.Run("no key", func(t *testing.T) {
+		_, _, err := SQLUpdate("users", newSQLUser(), newSQLUser())
+		assert.ErrorContains(t, err, "key field is required")
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		_, _, err := SQLUpdate("users", newSQLUser(), sqlUser{Name: "x"}, "uuid")
+		assert.ErrorContains(t, err, `key field "uuid" is not a column`)
+	})
+
+	t.Run("ignored key", func(t *testing.T) {
+		_, _, err := SQLUpdate("users", newSQLUser(), sqlUser{Name: "x"}, "cache")
+		assert.ErrorContains(t, err, "is not a column")
+	})
+
+	t.Run("different types", func(t *testing.T) {
+		_, _, err := SQLUpdate("users", newSQLUser(), other{}, "id")
+		assert.ErrorContains(t, err, "same type")
+	})
+
+	t.Run("not structs", func(t *testing.T) {
+		_, _, err := SQLUpdate("users", map[string]any{}, map[string]any{}, "id")
+		assert.ErrorContains(t, err, "same type")
+	})
+}
@@ -0,0 +1,221 @@
+package structdiff
+
+import (
+	"reflect"
+	"sort"
+)
+
+// WalkAction tells Walk how to continue after a Visitor callback.
+type WalkAction int
+
+const (
+	// Continue proceeds with the walk.
+	Continue WalkAction = iota
+
+	// SkipSubtree returned from Enter skips the contents of that struct or
+	// map, and Leave is not called for it. Returned from Change, it skips the
+	// remaining contents of the enclosing struct or map.
+	SkipSubtree
+
+	// Stop ends the walk. No further callbacks are made and Walk returns nil.
+	Stop
+)
+
+// Visitor receives the differences found by Walk as they are found.
+//
+// Enter and Leave bracket each pair of structs or maps that is compared,
+// including the top-level pair (with an empty path). Change reports a single
+// difference, with old and new in the same form as they appear in a patch;
+// old is nil for additions and new is nil for removals.
+//
+// Paths use the same keys as patches and are only valid for the duration of
+// the call; copy them to retain them.
+type Visitor interface {
+	Enter(path []string) WalkAction
+	Change(path []string, old, new any) WalkAction
+	Leave(path []string)
+}
+
+// Walk compares old and new using the same traversal and rules as Diff, and
+// reports the differences to v as they are found instead of building a patch.
+// Struct fields are visited in declaration order and map keys in sorted order.
+//
+// Walk returns nil when the traversal is complete or v returns Stop, or an
+// error if one occurs during diffing.
+func Walk(old, new any, v Visitor, opts ...Option) error {
+	o := newOptions(opts)
+	o.sortKeys = true
+	return newDiffer(o, visitorSink{v}, true).diffAny(old, new)
+}
+
+// visitorSink adapts a Visitor to a changeSink.
+type visitorSink struct {
+	v Visitor
+}
+
+func (s visitorSink) enter(path []string) WalkAction {
+	return s.v.Enter(path)
+}
+
+func (s visitorSink) change(path []string, kind ChangeKind, old, new any, typ reflect.Type) WalkAction {
+	return s.v.Change(path, old, new)
+}
+
+func (s visitorSink) leave(path []string) {
+	s.v.Leave(path)
+}
+
+// changeSink receives the events of a diff traversal, like a Visitor but with
+// the kind and type of each change. enter and leave bracket every struct or
+// map that is compared; change reports a single difference. The path slice is
+// only valid for the duration of the call.
+type changeSink interface {
+	enter(path []string) WalkAction
+	change(path []string, kind ChangeKind, old, new any, typ reflect.Type) WalkAction
+	leave(path []string)
+}
+
+// differ walks two values in the order described by DiffStructs and DiffMaps
+// and reports their differences to a changeSink.
+type differ struct {
+	o    *options
+	sink changeSink
+	path []string
+
+	// wantOld reports whether the sink uses old values, which are otherwise
+	// not computed
+	wantOld bool
+
+	// noValues reports whether the sink only needs to know that changes
+	// exist, so that new values are not computed either
+	noValues bool
+
+	// skipRest and stopped record SkipSubtree and Stop requests from change
+	skipRest bool
+	stopped  bool
+}
+
+func newDiffer(o *options, sink changeSink, wantOld bool) *differ {
+	return &differ{o: o, sink: sink, wantOld: wantOld}
+}
+
+// enter reports entering a container at the current path, and returns
+// whether its contents should be visited. If it returns true, the caller must
+// call leave when done.
+func (d *differ) enter() bool {
+	if d.stopped {
+		return false
+	}
+	switch d.sink.enter(d.path) {
+	case SkipSubtree:
+		return false
+	case Stop:
+		d.stopped = true
+		return false
+	}
+	return true
+}
+
+// leave reports leaving the container at the current path. A SkipSubtree
+// request from one of its changes ends with it.
+func (d *differ) leave() {
+	d.skipRest = false
+	if !d.stopped {
+		d.sink.leave(d.path)
+	}
+}
+
+// halted reports whether the rest of the current container should be skipped,
+// because the visitor returned SkipSubtree for a change in it or Stop.
+func (d *differ) halted() bool {
+	return d.skipRest || d.stopped
+}
+
+func (d *differ) push(key string) {
+	d.path = append(d.path, key)
+}
+
+func (d *differ) pop() {
+	d.path = d.path[:len(d.path)-1]
+}
+
+// change reports a change at the current path. hadOld and hasNew tell whether
+// the value is present on each side, which decides the kind of change: map
+// keys are present whatever their value, while nil struct fields, which
+// patches leave out, are not.
+func (d *differ) change(hadOld, hasNew bool, old, new any, typ reflect.Type) {
+	kind := Modified
+	if !hasNew {
+		kind = Removed
+	} else if !hadOld {
+		kind = Added
+	}
+	switch d.sink.change(d.path, kind, old, new, typ) {
+	case SkipSubtree:
+		d.skipRest = true
+	case Stop:
+		d.stopped = true
+	}
+}
+
+// emit reports a change at the current path extended by key.
+func (d *differ) emit(key string, hadOld, hasNew bool, old, new any, typ reflect.Type) {
+	d.push(key)
+	d.change(hadOld, hasNew, old, new, typ)
+	d.pop()
+}
+
+// oldValue returns the patch form of an old value, if the sink wants it.
+func (d *differ) oldValue(v reflect.Value) (any, error) {
+	if !d.wantOld {
+		return nil, nil
+	}
+	d.syncPath()
+	return toMapValue(v, d.o)
+}
+
+// newValue returns the patch form of a new value, unless the sink does not
+// want values.
+func (d *differ) newValue(v reflect.Value) (any, error) {
+	if d.noValues {
+		return nil, nil
+	}
+	d.syncPath()
+	return toMapValue(v, d.o)
+}
+
+// typeOf returns the dynamic type of new, or of old if new is nil.
+func typeOf(old, new any) reflect.Type {
+	if new != nil {
+		return reflect.TypeOf(new)
+	}
+	return reflect.TypeOf(old)
+}
+
+// isNilValue reports whether v is invalid or a nil pointer, map, slice or
+// interface.
+func isNilValue(v reflect.Value) bool {
+	if !v.IsValid() {
+		return true
+	}
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// sortedKeys returns the union of the keys of two maps in sorted order.
+func sortedKeys(a, b map[string]any) []string {
+	keys := make([]string, 0, len(a)+len(b))
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, inA := a[k]; !inA {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
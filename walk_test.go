@@ -0,0 +1,193 @@
+package structdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingVisitor records callbacks as strings and returns preset actions.
+type recordingVisitor struct {
+	events  []string
+	onEnter map[string]WalkAction
+	onLeaf  map[string]WalkAction
+}
+
+func (v *recordingVisitor) Enter(path []string) WalkAction {
+	p := strings.Join(path, ".")
+	v.events = append(v.events, "enter "+p)
+	return v.onEnter[p]
+}
+
+func (v *recordingVisitor) Change(path []string, old, new any) WalkAction {
+	p := strings.Join(path, ".")
+	v.events = append(v.events, "change "+p)
+	return v.onLeaf[p]
+}
+
+func (v *recordingVisitor) Leave(path []string) {
+	v.events = append(v.events, "leave "+strings.Join(path, "."))
+}
+
+type walkAddress struct {
+	Street string `json:"street"`
+	City   string `json:"city"`
+}
+
+type walkPerson struct {
+	Name    string         `json:"name"`
+	Address walkAddress    `json:"address"`
+	Meta    map[string]any `json:"meta"`
+	Age     int            `json:"age"`
+}
+
+func walkPair() (walkPerson, walkPerson) {
+	old := walkPerson{
+		Name:    "Alice",
+		Address: walkAddress{Street: "1 Main St", City: "NYC"},
+		Meta:    map[string]any{"a": 1, "b": 2},
+		Age:     30,
+	}
+	new := walkPerson{
+		Name:    "Alicia",
+		Address: walkAddress{Street: "2 Main St", City: "Boston"},
+		Meta:    map[string]any{"a": 1, "b": 3, "c": 4},
+		Age:     31,
+	}
+	return old, new
+}
+
+func TestWalk_Events(t *testing.T) {
+	old, new := walkPair()
+	v := &recordingVisitor{}
+
+	err := Walk(old, new, v)
+	require.NoError(t, err)
+
+	expected := []string{
+		"enter ",
+		"change name",
+		"enter address",
+		"change address.street",
+		"change address.city",
+		"leave address",
+		"enter meta",
+		"change meta.b",
+		"change meta.c",
+		"leave meta",
+		"change age",
+		"leave ",
+	}
+	assert.Equal(t, expected, v.events)
+}
+
+func TestWalk_SkipSubtreeFromEnter(t *testing.T) {
+	old, new := walkPair()
+	v := &recordingVisitor{onEnter: map[string]WalkAction{"address": SkipSubtree}}
+
+	err := Walk(old, new, v)
+	require.NoError(t, err)
+
+	assert.Contains(t, v.events, "enter address")
+	assert.NotContains(t, v.events, "change address.street")
+	assert.NotContains(t, v.events, "leave address")
+	assert.Contains(t, v.events, "change meta.b")
+}
+
+func TestWalk_SkipSubtreeFromChange(t *testing.T) {
+	old, new := walkPair()
+	v := &recordingVisitor{onLeaf: map[string]WalkAction{"address.street": SkipSubtree}}
+
+	err := Walk(old, new, v)
+	require.NoError(t, err)
+
+	// The rest of address is skipped, but the walk continues after it
+	assert.NotContains(t, v.events, "change address.city")
+	assert.Contains(t, v.events, "leave address")
+	assert.Contains(t, v.events, "change meta.b")
+	assert.Contains(t, v.events, "change age")
+}
+
+func TestWalk_Stop(t *testing.T) {
+	old, new := walkPair()
+	v := &recordingVisitor{onLeaf: map[string]WalkAction{"address.street": Stop}}
+
+	err := Walk(old, new, v)
+	require.NoError(t, err)
+
+	expected := []string{
+		"enter ",
+		"change name",
+		"enter address",
+		"change address.street",
+	}
+	assert.Equal(t, expected, v.events)
+}
+
+func TestWalk_StopFromEnter(t *testing.T) {
+	old, new := walkPair()
+	v := &recordingVisitor{onEnter: map[string]WalkAction{"": Stop}}
+
+	err := Walk(old, new, v)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"enter "}, v.events)
+}
+
+// countingVisitor counts changes without building a patch
+type countingVisitor struct {
+	count int
+}
+
+func (v *countingVisitor) Enter(path []string) WalkAction { return Continue }
+func (v *countingVisitor) Leave(path []string)            {}
+func (v *countingVisitor) Change(path []string, old, new any) WalkAction {
+	v.count++
+	return Continue
+}
+
+func TestWalk_MatchesDiff(t *testing.T) {
+	old, new := walkPair()
+
+	v := &countingVisitor{}
+	require.NoError(t, Walk(old, new, v))
+
+	patch, err := Diff(old, new)
+	require.NoError(t, err)
+	assert.Equal(t, len(ChangesFromPatch(patch.(map[string]any))), v.count)
+
+	// A patch builder walked by hand produces the same patch as Diff
+	b := &patchBuilder{}
+	require.NoError(t, Walk(old, new, b))
+	assert.Equal(t, patch, b.root)
+}
+
+func TestWalk_OldAndNewValues(t *testing.T) {
+	type pair struct{ old, new any }
+	var got []pair
+
+	v := &funcVisitor{change: func(path []string, old, new any) WalkAction {
+		got = append(got, pair{old, new})
+		return Continue
+	}}
+
+	err := Walk(map[string]any{"a": 1, "b": 2}, map[string]any{"b": 3, "c": 4}, v)
+	require.NoError(t, err)
+	assert.Equal(t, []pair{{1, nil}, {2, 3}, {nil, 4}}, got)
+}
+
+func TestWalk_Errors(t *testing.T) {
+	err := Walk(*newCycleTree("b"), *newCycleTree("c"), &countingVisitor{})
+	assert.ErrorIs(t, err, ErrCycle)
+}
+
+type funcVisitor struct {
+	change func(path []string, old, new any) WalkAction
+}
+
+func (v *funcVisitor) Enter(path []string) WalkAction { return Continue }
+func (v *funcVisitor) Leave(path []string)            {}
+func (v *funcVisitor) Change(path []string, old, new any) WalkAction {
+	return v.change(path, old, new)
+}